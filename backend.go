@@ -0,0 +1,17 @@
+package sendkeys
+
+// Backend is the keyboard device that KBWrap sends its key events to.
+// The default backend injects the events into the local operating system
+// using the keybd_event library. Other backends may target remote sessions,
+// virtual machines or test doubles.
+//
+// Key codes without a key, see KeyCode.ModifiersOnly, press and release
+// the modifier keys only.
+type Backend interface {
+	// Press presses the modifiers that are set in key followed by the key itself.
+	Press(key KeyCode) error
+	// Release releases the key followed by the modifiers that are set in key.
+	Release(key KeyCode) error
+	// Close releases all resources that are held by the backend.
+	Close() error
+}
@@ -0,0 +1,327 @@
+package sendkeys
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+var (
+	// ErrNoHIDUsage is returned when a key code cannot be translated into a USB HID usage.
+	ErrNoHIDUsage = errors.New("no hid usage for key code")
+	// ErrHIDRollover is returned when more than six keys are pressed at once.
+	ErrHIDRollover = errors.New("hid rollover: more than six keys pressed")
+)
+
+// modifier bits of the HID boot keyboard report
+const (
+	HIDLeftCtrl byte = 1 << iota
+	HIDLeftShift
+	HIDLeftAlt
+	HIDLeftGUI
+	HIDRightCtrl
+	HIDRightShift
+	HIDRightAlt
+	HIDRightGUI
+)
+
+// evdevHIDUsages maps linux evdev key codes, which are the key codes of KeyMapLinuxQuartz,
+// to the usages of the USB HID keyboard page.
+var evdevHIDUsages = map[int]byte{
+	30: 0x04, 48: 0x05, 46: 0x06, 32: 0x07, 18: 0x08, 33: 0x09, 34: 0x0a, 35: 0x0b, 23: 0x0c, // a - i
+	36: 0x0d, 37: 0x0e, 38: 0x0f, 50: 0x10, 49: 0x11, 24: 0x12, 25: 0x13, 16: 0x14, 19: 0x15, // j - r
+	31: 0x16, 20: 0x17, 22: 0x18, 47: 0x19, 17: 0x1a, 45: 0x1b, 21: 0x1c, 44: 0x1d, // s - z
+	2: 0x1e, 3: 0x1f, 4: 0x20, 5: 0x21, 6: 0x22, 7: 0x23, 8: 0x24, 9: 0x25, 10: 0x26, 11: 0x27, // 1 - 0
+	28: 0x28, 1: 0x29, 14: 0x2a, 15: 0x2b, 57: 0x2c, // Enter, Escape, BackSpace, Tab, Space
+	12: 0x2d, 13: 0x2e, 26: 0x2f, 27: 0x30, 43: 0x31, 39: 0x33, 40: 0x34, 41: 0x35, // - = [ ] \ ; ' `
+	51: 0x36, 52: 0x37, 53: 0x38, 58: 0x39, // , . / CapsLock
+	59: 0x3a, 60: 0x3b, 61: 0x3c, 62: 0x3d, 63: 0x3e, 64: 0x3f, 65: 0x40, 66: 0x41, 67: 0x42, 68: 0x43, // F1 - F10
+	87: 0x44, 88: 0x45, 99: 0x46, 70: 0x47, 119: 0x48, // F11, F12, PrintScreen, ScrollLock, Pause
+	110: 0x49, 102: 0x4a, 104: 0x4b, 111: 0x4c, 107: 0x4d, 109: 0x4e, // Insert, Home, PageUp, Delete, End, PageDown
+	106: 0x4f, 105: 0x50, 108: 0x51, 103: 0x52, // Right, Left, Down, Up
+	69: 0x53, 98: 0x54, 55: 0x55, 74: 0x56, 78: 0x57, 96: 0x58, // NumLock, numpad / * - + Enter
+	79: 0x59, 80: 0x5a, 81: 0x5b, 75: 0x5c, 76: 0x5d, 77: 0x5e, 71: 0x5f, 72: 0x60, 73: 0x61, 82: 0x62, // numpad 1 - 0
+	83: 0x63, 86: 0x64, 127: 0x65, 117: 0x67, // numpad decimal, non-US \, Menu, numpad =
+	183: 0x68, 184: 0x69, 185: 0x6a, 186: 0x6b, 187: 0x6c, 188: 0x6d, // F13 - F18
+	189: 0x6e, 190: 0x6f, 191: 0x70, 192: 0x71, 193: 0x72, 194: 0x73, // F19 - F24
+	113: 0x7f, 115: 0x80, 114: 0x81, // Mute, VolumeUp, VolumeDown
+	29: 0xe0, 42: 0xe1, 56: 0xe2, 125: 0xe3, 97: 0xe4, 54: 0xe5, 100: 0xe6, 126: 0xe7, // modifiers
+}
+
+// EvdevHIDUsage returns the USB HID keyboard usage of a linux evdev key code.
+func EvdevHIDUsage(code int) (byte, bool) {
+	usage, ok := evdevHIDUsages[code]
+	return usage, ok
+}
+
+// HIDReport is an 8 byte report of a USB HID boot keyboard:
+// the modifier bits, a reserved byte and up to six usages of pressed keys.
+type HIDReport [8]byte
+
+// Modifiers returns the modifier bits of the report.
+func (r HIDReport) Modifiers() byte {
+	return r[0]
+}
+
+// Usages returns the usages of the pressed keys.
+func (r HIDReport) Usages() []byte {
+	var usages []byte
+	for _, u := range r[2:] {
+		if u != 0 {
+			usages = append(usages, u)
+		}
+	}
+	return usages
+}
+
+func (r HIDReport) String() string {
+	return fmt.Sprintf("% x", r[:])
+}
+
+// HIDKey is a key of the USB HID keyboard page together with the modifier bits
+// that are pressed with it.
+type HIDKey struct {
+	Modifiers byte
+	Usage     byte
+}
+
+// hidKey translates a key code with the given translation of the code.
+func hidKey(key KeyCode, usage func(code int) (byte, bool)) (HIDKey, error) {
+	var (
+		u  byte
+		ok bool
+	)
+	switch {
+	case key.ModifiersOnly():
+		ok = true
+	case key.Code == 0 && key.Keysym != 0:
+		var code int
+		if code, ok = keysymEvdev[key.Keysym]; ok {
+			u, ok = evdevHIDUsages[code]
+		}
+	default:
+		u, ok = usage(key.Code)
+	}
+	if !ok {
+		return HIDKey{}, fmt.Errorf("%w: %s", ErrNoHIDUsage, key)
+	}
+
+	var mods byte
+	right := key.ModifierSide == SideRight
+	for _, m := range []struct {
+		ok          bool
+		left, right byte
+	}{
+		{key.ModifierCTRL, HIDLeftCtrl, HIDRightCtrl},
+		{key.ModifierSHIFT, HIDLeftShift, HIDRightShift},
+		{key.ModifierALT, HIDLeftAlt, HIDRightAlt},
+		{key.ModifierSuper, HIDLeftGUI, HIDRightGUI},
+		{key.ModifierALTGR, HIDRightAlt, HIDRightAlt},
+	} {
+		switch {
+		case !m.ok:
+		case right:
+			mods |= m.right
+		default:
+			mods |= m.left
+		}
+	}
+	if u >= 0xe0 && u <= 0xe7 {
+		// modifier keys are reported as modifier bits
+		mods |= 1 << (u - 0xe0)
+		u = 0
+	}
+	return HIDKey{Modifiers: mods, Usage: u}, nil
+}
+
+// HIDKeyMap maps characters to USB HID keys of a keyboard layout.
+type HIDKeyMap map[rune]HIDKey
+
+// NewHIDKeyMap converts a KeyMap with linux evdev key codes, e.g. KeyMapLinuxQuartz
+// or a layout of LoadXKBLayout, into a HIDKeyMap. Characters whose keys have no
+// HID usage are left out.
+func NewHIDKeyMap(keyMap KeyMap) HIDKeyMap {
+	result := make(HIDKeyMap, len(keyMap))
+	for r, code := range keyMap {
+		key, err := hidKey(code, EvdevHIDUsage)
+		if err == nil && key.Usage != 0 {
+			result[r] = key
+		}
+	}
+	return result
+}
+
+// HIDKeyMapUS returns the HIDKeyMap of a US keyboard.
+func HIDKeyMapUS() HIDKeyMap {
+	return NewHIDKeyMap(KeyMapLinuxQuartz())
+}
+
+// Reports returns the reports that type the text: the modifiers are pressed before
+// and released after every key, and all keys are released in between.
+func (m HIDKeyMap) Reports(text string) ([]HIDReport, error) {
+	var (
+		reports []HIDReport
+		errs    []error
+		s       textScanner
+	)
+	for offset, r := range text {
+		u := s.pos(offset, r)
+		key, ok := m[r]
+		if !ok {
+			errs = append(errs, newUnmappedError(u, ErrKeyMappingNotFound))
+			continue
+		}
+		if key.Modifiers != 0 {
+			reports = append(reports, HIDReport{0: key.Modifiers})
+		}
+		reports = append(reports, HIDReport{0: key.Modifiers, 2: key.Usage})
+		if key.Modifiers != 0 {
+			reports = append(reports, HIDReport{0: key.Modifiers})
+		}
+		reports = append(reports, HIDReport{})
+	}
+	return reports, errors.Join(errs...)
+}
+
+// HIDConfig configures the HIDBackend.
+type HIDConfig struct {
+	// Usage translates the key code of a KeyCode into a USB HID keyboard usage.
+	// Defaults to EvdevHIDUsage, which expects the key codes of KeyMapLinuxQuartz.
+	// Keysyms of named keys, see WithKeysymMap, are translated as well.
+	Usage func(code int) (byte, bool)
+}
+
+// HIDBackend is a Backend that writes USB HID boot keyboard reports, e.g. to the
+// /dev/hidg0 device of a USB gadget or to a hardware KVM. Every report is written
+// with a single call to Write. Modifiers are reported before the key is pressed
+// and after it was released.
+type HIDBackend struct {
+	w     io.Writer
+	usage func(code int) (byte, bool)
+
+	mu     sync.Mutex
+	mods   [8]int       // number of pressed keys that hold each modifier bit
+	keys   []byte       // pressed usages in the order they were pressed
+	counts map[byte]int // number of presses of each usage
+}
+
+// NewHIDBackend creates a backend that writes the reports to w.
+func NewHIDBackend(w io.Writer, cfg HIDConfig) *HIDBackend {
+	b := &HIDBackend{
+		w:      w,
+		usage:  cfg.Usage,
+		counts: map[byte]int{},
+	}
+	if b.usage == nil {
+		b.usage = EvdevHIDUsage
+	}
+	return b
+}
+
+// report returns the current report.
+func (b *HIDBackend) report() HIDReport {
+	var r HIDReport
+	for bit, n := range b.mods {
+		if n > 0 {
+			r[0] |= 1 << bit
+		}
+	}
+	copy(r[2:], b.keys)
+	return r
+}
+
+func (b *HIDBackend) write() error {
+	r := b.report()
+	_, err := b.w.Write(r[:])
+	return err
+}
+
+// modifiers adds delta to the counters of the modifier bits and reports whether a bit changed.
+func (b *HIDBackend) modifiers(mods byte, delta int) (changed bool) {
+	for bit := range b.mods {
+		if mods&(1<<bit) == 0 {
+			continue
+		}
+		before := b.mods[bit] > 0
+		b.mods[bit] += delta
+		if b.mods[bit] < 0 {
+			b.mods[bit] = 0
+		}
+		changed = changed || before != (b.mods[bit] > 0)
+	}
+	return changed
+}
+
+// Press reports the modifiers that are set in key followed by the key itself.
+func (b *HIDBackend) Press(key KeyCode) error {
+	k, err := hidKey(key, b.usage)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if k.Usage != 0 && b.counts[k.Usage] == 0 && len(b.keys) >= 6 {
+		return ErrHIDRollover
+	}
+	// the key counts as pressed even if a report cannot be written,
+	// so that the reports stay consistent with the following Release
+	var errs []error
+	if b.modifiers(k.Modifiers, 1) && k.Usage != 0 {
+		errs = append(errs, b.write())
+	}
+	if k.Usage != 0 {
+		if b.counts[k.Usage] == 0 {
+			b.keys = append(b.keys, k.Usage)
+		}
+		b.counts[k.Usage]++
+	}
+	return errors.Join(append(errs, b.write())...)
+}
+
+// Release reports the release of the key followed by the modifiers that are set in key.
+func (b *HIDBackend) Release(key KeyCode) error {
+	k, err := hidKey(key, b.usage)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// the key and its modifiers are released even if a report cannot be written,
+	// so that they are not part of the following reports
+	var errs []error
+	if k.Usage != 0 && b.counts[k.Usage] > 0 {
+		b.counts[k.Usage]--
+		if b.counts[k.Usage] == 0 {
+			delete(b.counts, k.Usage)
+			for i, u := range b.keys {
+				if u == k.Usage {
+					b.keys = append(b.keys[:i], b.keys[i+1:]...)
+					break
+				}
+			}
+			errs = append(errs, b.write())
+		}
+	}
+	if b.modifiers(k.Modifiers, -1) || k.Usage == 0 {
+		errs = append(errs, b.write())
+	}
+	return errors.Join(errs...)
+}
+
+// Close releases all keys and closes the writer in case it is an io.Closer.
+func (b *HIDBackend) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.mods = [8]int{}
+	b.keys = nil
+	b.counts = map[byte]int{}
+	err := b.write()
+	if c, ok := b.w.(io.Closer); ok {
+		return errors.Join(err, c.Close())
+	}
+	return err
+}
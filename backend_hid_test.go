@@ -0,0 +1,235 @@
+package sendkeys
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// hidReports splits the written bytes into reports.
+func hidReports(t *testing.T, b []byte) []HIDReport {
+	t.Helper()
+	if len(b)%8 != 0 {
+		t.Fatalf("expected reports of 8 bytes, got %d bytes", len(b))
+	}
+	reports := make([]HIDReport, len(b)/8)
+	for i := range reports {
+		copy(reports[i][:], b[i*8:])
+	}
+	return reports
+}
+
+func newHIDKBWrap(t *testing.T, opts ...KBOpt) (*KBWrap, *bytes.Buffer) {
+	t.Helper()
+	var buf bytes.Buffer
+	opts = append([]KBOpt{
+		WithBackend(NewHIDBackend(&buf, HIDConfig{})),
+		WithKeyMap(KeyMapLinuxQuartz()),
+		KeystrokeDuration(0),
+		DelayAfter(0),
+	}, opts...)
+	k, err := NewKBWrapWithOptions(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k, &buf
+}
+
+func TestHIDBackend(t *testing.T) {
+	k, buf := newHIDKBWrap(t)
+
+	err := errors.Join(
+		k.Type("Hi!\n"),
+		k.KeyDown(ShiftKeyCode(29)),
+		k.Type("a"),
+		k.KeyUp(ShiftKeyCode(29)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []HIDReport{
+		{0: HIDLeftShift}, {0: HIDLeftShift, 2: 0x0b}, {0: HIDLeftShift}, {}, // H
+		{2: 0x0c}, {}, // i
+		{0: HIDLeftShift}, {0: HIDLeftShift, 2: 0x1e}, {0: HIDLeftShift}, {}, // !
+		{2: 0x28}, {}, // Enter
+		// a while shift+ctrl is held down
+		{0: HIDLeftShift | HIDLeftCtrl},
+		{0: HIDLeftShift | HIDLeftCtrl, 2: 0x04}, {0: HIDLeftShift | HIDLeftCtrl},
+		{},
+	}
+	if reports := hidReports(t, buf.Bytes()); fmt.Sprint(reports) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, reports)
+	}
+
+	buf.Reset()
+	err = k.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reports := hidReports(t, buf.Bytes()); len(reports) == 0 || reports[len(reports)-1] != (HIDReport{}) {
+		t.Errorf("expected all keys to be released, got %v", reports)
+	}
+}
+
+func TestHIDBackendRollover(t *testing.T) {
+	var buf bytes.Buffer
+	b := NewHIDBackend(&buf, HIDConfig{})
+
+	codes := []int{30, 48, 46, 32, 18, 33, 34}
+	for _, code := range codes[:6] {
+		err := b.Press(SimpleKeyCode(code))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := b.Press(SimpleKeyCode(codes[6]))
+	if !errors.Is(err, ErrHIDRollover) {
+		t.Errorf("expected ErrHIDRollover, got %v", err)
+	}
+
+	// the modifier does not use a slot
+	err = errors.Join(b.Press(SimpleKeyCode(42)), b.Release(SimpleKeyCode(48)), b.Press(SimpleKeyCode(codes[6])))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reports := hidReports(t, buf.Bytes())
+	last := reports[len(reports)-1]
+	if last.Modifiers() != HIDLeftShift || fmt.Sprint(last.Usages()) != fmt.Sprint([]byte{0x04, 0x06, 0x07, 0x08, 0x09, 0x0a}) {
+		t.Errorf("unexpected report %v", last)
+	}
+
+	err = b.Press(SimpleKeyCode(1000))
+	if !errors.Is(err, ErrNoHIDUsage) {
+		t.Errorf("expected ErrNoHIDUsage, got %v", err)
+	}
+}
+
+// failingWriter fails the writes whose numbers are set in fail.
+type failingWriter struct {
+	bytes.Buffer
+	n    int
+	fail map[int]bool
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.n++
+	if w.fail[w.n] {
+		return 0, errBroken
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestHIDBackendWriteError(t *testing.T) {
+	// the reports of shift, shift+a, shift and of the release of shift fail
+	w := &failingWriter{fail: map[int]bool{1: true, 3: true, 4: true}}
+	b := NewHIDBackend(w, HIDConfig{})
+
+	key := ShiftKeyCode(30)
+	if err := b.Press(key); !errors.Is(err, errBroken) {
+		t.Errorf("expected errBroken, got %v", err)
+	}
+	if err := b.Release(key); !errors.Is(err, errBroken) {
+		t.Errorf("expected errBroken, got %v", err)
+	}
+	err := errors.Join(b.Press(SimpleKeyCode(48)), b.Release(SimpleKeyCode(48)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// shift is not stuck
+	expected := []HIDReport{{0: HIDLeftShift, 2: 0x04}, {2: 0x05}, {}}
+	if reports := hidReports(t, w.Bytes()); fmt.Sprint(reports) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, reports)
+	}
+}
+
+func TestHIDBackendModifiers(t *testing.T) {
+	for _, tc := range []struct {
+		key      KeyCode
+		expected byte
+	}{
+		{ShiftKeyCode(30), HIDLeftShift},
+		{KeyCode{Code: 30, ModifierSHIFT: true, ModifierSide: SideRight}, HIDRightShift},
+		{KeyCode{Code: 30, ModifierCTRL: true, ModifierSuper: true}, HIDLeftCtrl | HIDLeftGUI},
+		{KeyCode{Code: 30, ModifierALT: true, ModifierSide: SideRight}, HIDRightAlt},
+		{AltGrKeyCode(18), HIDRightAlt},
+		{SimpleKeyCode(126), HIDRightGUI},
+		{KeyCode{ModifierCTRL: true, ModifierSide: SideRight}, HIDRightCtrl},
+	} {
+		var buf bytes.Buffer
+		b := NewHIDBackend(&buf, HIDConfig{})
+		err := errors.Join(b.Press(tc.key), b.Release(tc.key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		reports := hidReports(t, buf.Bytes())
+		if reports[0].Modifiers() != tc.expected || reports[len(reports)-1] != (HIDReport{}) {
+			t.Errorf("%v: expected modifiers %08b, got %v", tc.key, tc.expected, reports)
+		}
+	}
+}
+
+func TestHIDBackendKeysyms(t *testing.T) {
+	k, buf := newHIDKBWrap(t, WithKeysymMap(nil), WithKeyMap(nil))
+
+	err := errors.Join(k.Press(KeyF5, ModCtrl), k.Chord("alt+tab"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []HIDReport{
+		{0: HIDLeftCtrl}, {0: HIDLeftCtrl, 2: 0x3e}, {0: HIDLeftCtrl}, {},
+		{0: HIDLeftAlt}, {0: HIDLeftAlt, 2: 0x2b}, {0: HIDLeftAlt}, {},
+	}
+	if reports := hidReports(t, buf.Bytes()); fmt.Sprint(reports) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, reports)
+	}
+}
+
+func TestHIDKeyMap(t *testing.T) {
+	us := HIDKeyMapUS()
+	for r, expected := range map[rune]HIDKey{
+		'a':  {Usage: 0x04},
+		'Z':  {HIDLeftShift, 0x1d},
+		'0':  {Usage: 0x27},
+		'@':  {HIDLeftShift, 0x1f},
+		'\n': {Usage: 0x28},
+		' ':  {Usage: 0x2c},
+	} {
+		if us[r] != expected {
+			t.Errorf("us %q: expected %v, got %v", r, expected, us[r])
+		}
+	}
+
+	keyMap, err := ParseXKBLayout(xkbTestSymbols, "de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	de := NewHIDKeyMap(keyMap)
+	for r, expected := range map[rune]HIDKey{
+		'z': {Usage: 0x1c},
+		'y': {Usage: 0x1d},
+		'@': {HIDRightAlt, 0x14},
+		'€': {HIDRightAlt, 0x08},
+		'µ': {HIDRightAlt, 0x10},
+	} {
+		if de[r] != expected {
+			t.Errorf("de %q: expected %v, got %v", r, expected, de[r])
+		}
+	}
+
+	reports, err := us.Reports("Ab\x01c")
+	var unmapped *UnmappedError
+	if !errors.As(err, &unmapped) || unmapped.Rune != '\x01' || unmapped.Col != 3 {
+		t.Errorf("expected an error for the unmapped character, got %v", err)
+	}
+	expected := []HIDReport{
+		{0: HIDLeftShift}, {0: HIDLeftShift, 2: 0x04}, {0: HIDLeftShift}, {},
+		{2: 0x05}, {},
+		{2: 0x06}, {},
+	}
+	if fmt.Sprint(reports) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, reports)
+	}
+}
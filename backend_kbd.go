@@ -0,0 +1,131 @@
+package sendkeys
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	kbd "github.com/micmonay/keybd_event"
+)
+
+// ErrKeysymNotSupported is returned by the default Backend for key codes that consist
+// of a keysym only, see WithKeysymMap.
+var ErrKeysymNotSupported = errors.New("keysyms are not supported by the backend")
+
+// kbdBackend is the default Backend which uses the keybd_event library
+// in order to simulate key events on the local machine.
+type kbdBackend struct {
+	d kbd.KeyBonding
+}
+
+func newKbdBackend() (*kbdBackend, error) {
+	d, err := kbd.NewKeyBonding()
+	if err != nil {
+		return nil, err
+	}
+	return &kbdBackend{d: d}, nil
+}
+
+// set prepares the key bonding for the given key.
+// Modifiers of a specific side are sent as regular keys, as the keybd_event
+// library does not support all of them, in case the platform supports it.
+func (b *kbdBackend) set(key KeyCode, release bool) {
+	b.d.Clear()
+	if key.ModifiersOnly() {
+		codes := onlyModifierKeys(key)
+		if release {
+			slices.Reverse(codes)
+		}
+		b.d.SetKeys(codes...)
+		return
+	}
+	b.d.HasALTGR(key.ModifierALTGR)
+
+	modifiers := sideModifierKeys(key)
+	if modifiers == nil {
+		b.d.HasALT(key.ModifierALT)
+		b.d.HasSuper(key.ModifierSuper)
+		b.d.HasCTRL(key.ModifierCTRL)
+		b.d.HasSHIFT(key.ModifierSHIFT)
+		b.d.SetKeys(key.Code)
+		return
+	}
+	if release {
+		// release the key before its modifiers
+		b.d.SetKeys(append([]int{key.Code}, modifiers...)...)
+		return
+	}
+	b.d.SetKeys(append(modifiers, key.Code)...)
+}
+
+// sideModifierKeys returns the key codes of the modifiers of the key
+// or nil, in case the key has no side or the platform does not support sides.
+func sideModifierKeys(key KeyCode) []int {
+	var codes []int
+	switch key.ModifierSide {
+	case SideLeft:
+		codes = leftModifierKeys
+	case SideRight:
+		codes = rightModifierKeys
+	}
+	if codes == nil {
+		return nil
+	}
+
+	result := []int{}
+	for i, ok := range []bool{key.ModifierSuper, key.ModifierALT, key.ModifierCTRL, key.ModifierSHIFT} {
+		if ok {
+			result = append(result, codes[i])
+		}
+	}
+	return result
+}
+
+// onlyModifierKeys returns the key codes of the modifier keys of a key code without key.
+func onlyModifierKeys(key KeyCode) []int {
+	codes := sideModifierKeys(key)
+	if codes == nil {
+		for i, ok := range []bool{key.ModifierSuper, key.ModifierALT, key.ModifierCTRL, key.ModifierSHIFT} {
+			if ok {
+				codes = append(codes, modifierKeys[i])
+			}
+		}
+	}
+	if key.ModifierALTGR {
+		codes = append(codes, modifierKeys[4])
+	}
+	return codes
+}
+
+// checkKeysym rejects key codes that consist of a keysym only,
+// as they would be sent as key code 0.
+func checkKeysym(key KeyCode) error {
+	if key.Code == 0 && key.Keysym != 0 {
+		return fmt.Errorf("%w: %s", ErrKeysymNotSupported, key)
+	}
+	return nil
+}
+
+func (b *kbdBackend) Press(key KeyCode) error {
+	err := checkKeysym(key)
+	if err != nil {
+		return err
+	}
+	b.set(key, false)
+	return b.d.Press()
+}
+
+func (b *kbdBackend) Release(key KeyCode) error {
+	err := checkKeysym(key)
+	if err != nil {
+		return err
+	}
+	b.set(key, true)
+	defer b.d.Clear()
+	return b.d.Release()
+}
+
+func (b *kbdBackend) Close() error {
+	b.d.Clear()
+	return nil
+}
@@ -0,0 +1,33 @@
+package sendkeys
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestOnlyModifierKeys(t *testing.T) {
+	tests := map[KeyCode][]int{
+		{ModifierCTRL: true, ModifierSHIFT: true}:  {modifierKeys[2], modifierKeys[3]},
+		{ModifierSuper: true, ModifierALTGR: true}: {modifierKeys[0], modifierKeys[4]},
+	}
+	if rightModifierKeys != nil {
+		tests[KeyCode{ModifierSHIFT: true, ModifierSide: SideRight}] = []int{rightModifierKeys[3]}
+	}
+	for key, expected := range tests {
+		if codes := onlyModifierKeys(key); fmt.Sprint(codes) != fmt.Sprint(expected) {
+			t.Errorf("%v: expected %v, got %v", key, expected, codes)
+		}
+	}
+}
+
+func TestKbdBackendKeysym(t *testing.T) {
+	b := &kbdBackend{}
+	key := KeysymKeyCode(0xffc2)
+	if err := b.Press(key); !errors.Is(err, ErrKeysymNotSupported) {
+		t.Errorf("press: expected ErrKeysymNotSupported, got %v", err)
+	}
+	if err := b.Release(key); !errors.Is(err, ErrKeysymNotSupported) {
+		t.Errorf("release: expected ErrKeysymNotSupported, got %v", err)
+	}
+}
@@ -0,0 +1,370 @@
+package sendkeys
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrQEMU is returned when the QEMU monitor rejects a command.
+	ErrQEMU = errors.New("qemu monitor error")
+	// ErrNoQCode is returned when a key code cannot be translated into a QEMU qcode.
+	ErrNoQCode = errors.New("no qcode for key code")
+)
+
+// hmpPrompt is the prompt of the human monitor.
+const hmpPrompt = "(qemu) "
+
+// evdevQCodes maps linux evdev key codes, which are the key codes of KeyMapLinuxQuartz,
+// to QEMU qcodes.
+var evdevQCodes = map[int]string{
+	1: "esc", 2: "1", 3: "2", 4: "3", 5: "4", 6: "5", 7: "6", 8: "7", 9: "8", 10: "9", 11: "0",
+	12: "minus", 13: "equal", 14: "backspace", 15: "tab",
+	16: "q", 17: "w", 18: "e", 19: "r", 20: "t", 21: "y", 22: "u", 23: "i", 24: "o", 25: "p",
+	26: "bracket_left", 27: "bracket_right", 28: "ret", 29: "ctrl",
+	30: "a", 31: "s", 32: "d", 33: "f", 34: "g", 35: "h", 36: "j", 37: "k", 38: "l",
+	39: "semicolon", 40: "apostrophe", 41: "grave_accent", 42: "shift", 43: "backslash",
+	44: "z", 45: "x", 46: "c", 47: "v", 48: "b", 49: "n", 50: "m",
+	51: "comma", 52: "dot", 53: "slash", 54: "shift_r", 55: "kp_multiply", 56: "alt", 57: "spc",
+	58: "caps_lock", 59: "f1", 60: "f2", 61: "f3", 62: "f4", 63: "f5", 64: "f6", 65: "f7",
+	66: "f8", 67: "f9", 68: "f10", 69: "num_lock", 70: "scroll_lock",
+	71: "kp_7", 72: "kp_8", 73: "kp_9", 74: "kp_subtract", 75: "kp_4", 76: "kp_5", 77: "kp_6",
+	78: "kp_add", 79: "kp_1", 80: "kp_2", 81: "kp_3", 82: "kp_0", 83: "kp_decimal",
+	86: "less", 87: "f11", 88: "f12", 96: "kp_enter", 97: "ctrl_r", 98: "kp_divide",
+	99: "sysrq", 100: "alt_r", 102: "home", 103: "up", 104: "pgup", 105: "left", 106: "right",
+	107: "end", 108: "down", 109: "pgdn", 110: "insert", 111: "delete",
+	113: "audiomute", 114: "volumedown", 115: "volumeup", 117: "kp_equals", 119: "pause",
+	125: "meta_l", 126: "meta_r", 127: "compose",
+	163: "audionext", 164: "audioplay", 165: "audioprev", 166: "audiostop",
+	183: "f13", 184: "f14", 185: "f15", 186: "f16", 187: "f17", 188: "f18",
+	189: "f19", 190: "f20", 191: "f21", 192: "f22", 193: "f23", 194: "f24",
+}
+
+// EvdevQCode returns the QEMU qcode of a linux evdev key code.
+func EvdevQCode(code int) (string, bool) {
+	qcode, ok := evdevQCodes[code]
+	return qcode, ok
+}
+
+// QEMUConfig configures the connection to a QEMU monitor.
+type QEMUConfig struct {
+	// HMP selects the human monitor and its sendkey command
+	// instead of the QMP send-key command.
+	HMP bool
+	// QCode translates the key code of a KeyCode into a QEMU qcode.
+	// Modifiers are sent as separate keys. Defaults to EvdevQCode, which expects
+	// the key codes of KeyMapLinuxQuartz. Keysyms of named keys, see WithKeysymMap,
+	// are translated as well.
+	QCode func(code int) (string, bool)
+}
+
+// QEMUBackend is a Backend that sends key events to a virtual machine through
+// the QMP send-key command or the sendkey command of the human monitor (HMP).
+//
+// Both commands press and release a combination of keys at once, so the keys are
+// sent when they are released, and held down for as long as they were pressed.
+// Keys that are still held down, e.g. with KBWrap.KeyDown, are added to the
+// combination of every other key.
+type QEMUBackend struct {
+	conn  net.Conn
+	r     *bufio.Reader
+	hmp   bool
+	qcode func(code int) (string, bool)
+
+	mu      sync.Mutex
+	pressed []KeyCode
+	since   map[KeyCode]time.Time
+}
+
+// DialQEMU connects to a QEMU monitor, network is either "unix" or "tcp",
+// e.g. for a monitor started with -qmp unix:/tmp/qmp.sock,server,nowait.
+func DialQEMU(ctx context.Context, network, address string, cfg QEMUConfig) (*QEMUBackend, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	b, err := NewQEMUBackend(ctx, conn, cfg)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+// NewQEMUBackend performs the handshake of the monitor on an established connection.
+// The handshake is aborted when the context is cancelled.
+func NewQEMUBackend(ctx context.Context, conn net.Conn, cfg QEMUConfig) (*QEMUBackend, error) {
+	b := &QEMUBackend{
+		conn:  conn,
+		r:     bufio.NewReader(conn),
+		hmp:   cfg.HMP,
+		qcode: cfg.QCode,
+		since: map[KeyCode]time.Time{},
+	}
+	if b.qcode == nil {
+		b.qcode = EvdevQCode
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		// unblock reads and writes of the handshake
+		_ = conn.Close()
+	})
+	var err error
+	if b.hmp {
+		_, err = b.hmpResponse()
+	} else {
+		err = b.qmpHandshake()
+	}
+	if !stop() {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// qmpMessage is a message of the QEMU machine protocol.
+type qmpMessage struct {
+	QMP    json.RawMessage `json:"QMP,omitempty"`
+	Event  string          `json:"event,omitempty"`
+	Return json.RawMessage `json:"return,omitempty"`
+	Error  *struct {
+		Class string `json:"class"`
+		Desc  string `json:"desc"`
+	} `json:"error,omitempty"`
+}
+
+// qmpHandshake reads the greeting and enters the command mode.
+func (b *QEMUBackend) qmpHandshake() error {
+	var greeting qmpMessage
+	err := b.qmpRead(&greeting)
+	if err != nil {
+		return err
+	}
+	if greeting.QMP == nil {
+		return fmt.Errorf("%w: unexpected greeting", ErrQEMU)
+	}
+	return b.qmpExecute("qmp_capabilities", nil)
+}
+
+func (b *QEMUBackend) qmpRead(msg *qmpMessage) error {
+	line, err := b.r.ReadBytes('\n')
+	if err != nil {
+		return err
+	}
+	*msg = qmpMessage{}
+	return json.Unmarshal(line, msg)
+}
+
+// qmpExecute executes a command and waits for its response, events are skipped.
+func (b *QEMUBackend) qmpExecute(command string, arguments any) error {
+	cmd := struct {
+		Execute   string `json:"execute"`
+		Arguments any    `json:"arguments,omitempty"`
+	}{command, arguments}
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return err
+	}
+	_, err = b.conn.Write(append(data, '\n'))
+	if err != nil {
+		return err
+	}
+
+	for {
+		var msg qmpMessage
+		err = b.qmpRead(&msg)
+		if err != nil {
+			return err
+		}
+		switch {
+		case msg.Error != nil:
+			return fmt.Errorf("%w: %s: %s", ErrQEMU, msg.Error.Class, msg.Error.Desc)
+		case msg.Return != nil:
+			return nil
+		}
+	}
+}
+
+// hmpResponse reads the output of a command up to the next prompt.
+func (b *QEMUBackend) hmpResponse() (string, error) {
+	var sb strings.Builder
+	for {
+		c, err := b.r.ReadByte()
+		if err != nil {
+			return sb.String(), err
+		}
+		sb.WriteByte(c)
+		if strings.HasSuffix(sb.String(), hmpPrompt) {
+			return strings.TrimSuffix(sb.String(), hmpPrompt), nil
+		}
+	}
+}
+
+// hmpExecute executes a command of the human monitor. Any output
+// besides the echo of the command is treated as error message.
+func (b *QEMUBackend) hmpExecute(command string) error {
+	_, err := b.conn.Write([]byte(command + "\n"))
+	if err != nil {
+		return err
+	}
+	output, err := b.hmpResponse()
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && line != command && !strings.HasPrefix(line, "\x1b") {
+			return fmt.Errorf("%w: %s", ErrQEMU, line)
+		}
+	}
+	return nil
+}
+
+// qcodes returns the qcodes of the modifiers followed by the qcode of the key, if any.
+func (b *QEMUBackend) qcodes(key KeyCode) ([]string, error) {
+	var (
+		qcode string
+		ok    bool
+	)
+	switch {
+	case key.ModifiersOnly():
+		ok = true
+	case key.Code == 0 && key.Keysym != 0:
+		var code int
+		if code, ok = keysymEvdev[key.Keysym]; ok {
+			qcode, ok = evdevQCodes[code]
+		}
+	default:
+		qcode, ok = b.qcode(key.Code)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoQCode, key)
+	}
+
+	right := key.ModifierSide == SideRight
+	var qcodes []string
+	for _, m := range []struct {
+		ok          bool
+		left, right string
+	}{
+		{key.ModifierSuper, "meta_l", "meta_r"},
+		{key.ModifierCTRL, "ctrl", "ctrl_r"},
+		{key.ModifierALT, "alt", "alt_r"},
+		{key.ModifierALTGR, "alt_r", "alt_r"},
+		{key.ModifierSHIFT, "shift", "shift_r"},
+	} {
+		switch {
+		case !m.ok:
+		case right:
+			qcodes = append(qcodes, m.right)
+		default:
+			qcodes = append(qcodes, m.left)
+		}
+	}
+	if qcode == "" {
+		return qcodes, nil
+	}
+	return append(qcodes, qcode), nil
+}
+
+// Press marks the key as pressed, it is sent when it is released.
+func (b *QEMUBackend) Press(key KeyCode) error {
+	_, err := b.qcodes(key)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.since[key]; !ok {
+		b.pressed = append(b.pressed, key)
+	}
+	b.since[key] = time.Now()
+	return nil
+}
+
+// Release sends the key together with its modifiers and all keys that are still
+// held down. The keys are held down for as long as the key was pressed.
+func (b *QEMUBackend) Release(key KeyCode) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var hold time.Duration
+	if since, ok := b.since[key]; ok {
+		hold = time.Since(since)
+	}
+	var keys []string
+	for i := 0; i < len(b.pressed); i++ {
+		if b.pressed[i] == key {
+			b.pressed = append(b.pressed[:i], b.pressed[i+1:]...)
+			i--
+			continue
+		}
+		qcodes, err := b.qcodes(b.pressed[i])
+		if err != nil {
+			return err
+		}
+		keys = appendMissing(keys, qcodes...)
+	}
+	delete(b.since, key)
+
+	qcodes, err := b.qcodes(key)
+	if err != nil {
+		return err
+	}
+	keys = appendMissing(keys, qcodes...)
+	return b.sendKey(keys, hold)
+}
+
+// appendMissing appends all values that are not part of the slice yet.
+func appendMissing(values []string, add ...string) []string {
+	for _, a := range add {
+		found := false
+		for _, v := range values {
+			if v == a {
+				found = true
+				break
+			}
+		}
+		if !found {
+			values = append(values, a)
+		}
+	}
+	return values
+}
+
+// sendKey presses the qcodes at once and releases them after the hold time.
+func (b *QEMUBackend) sendKey(qcodes []string, hold time.Duration) error {
+	ms := hold.Milliseconds()
+	if b.hmp {
+		return b.hmpExecute(fmt.Sprintf("sendkey %s %d", strings.Join(qcodes, "-"), ms))
+	}
+
+	type keyValue struct {
+		Type string `json:"type"`
+		Data string `json:"data"`
+	}
+	keys := make([]keyValue, 0, len(qcodes))
+	for _, q := range qcodes {
+		keys = append(keys, keyValue{Type: "qcode", Data: q})
+	}
+	return b.qmpExecute("send-key", struct {
+		Keys     []keyValue `json:"keys"`
+		HoldTime int64      `json:"hold-time"`
+	}{keys, ms})
+}
+
+// Close closes the connection to the monitor.
+func (b *QEMUBackend) Close() error {
+	return b.conn.Close()
+}
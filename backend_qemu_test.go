@@ -0,0 +1,268 @@
+package sendkeys
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// qemuServer is a fake QEMU monitor that records the received key combinations.
+type qemuServer struct {
+	hmp  bool
+	fail string // key combination that is rejected
+
+	mu   sync.Mutex
+	keys []string // e.g. "shift-h" for QMP and HMP
+	hold []time.Duration
+	err  error
+	done chan struct{}
+}
+
+func (s *qemuServer) Keys() []string {
+	<-s.done
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keys
+}
+
+func (s *qemuServer) record(keys string, hold time.Duration) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = append(s.keys, keys)
+	s.hold = append(s.hold, hold)
+	return keys != s.fail
+}
+
+func (s *qemuServer) serve(conn net.Conn) {
+	defer close(s.done)
+	defer conn.Close()
+
+	var err error
+	if s.hmp {
+		err = s.serveHMP(conn)
+	} else {
+		err = s.serveQMP(conn)
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		s.err = err
+	}
+}
+
+func (s *qemuServer) serveQMP(conn net.Conn) error {
+	_, err := io.WriteString(conn, `{"QMP": {"version": {"qemu": {"micro": 0, "minor": 2, "major": 8}}, "capabilities": []}}`+"\r\n")
+	if err != nil {
+		return err
+	}
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			return err
+		}
+		var cmd struct {
+			Execute   string `json:"execute"`
+			Arguments struct {
+				Keys []struct {
+					Type string `json:"type"`
+					Data string `json:"data"`
+				} `json:"keys"`
+				HoldTime int64 `json:"hold-time"`
+			} `json:"arguments"`
+		}
+		err = json.Unmarshal(line, &cmd)
+		if err != nil {
+			return err
+		}
+
+		// events are sent at any time
+		_, err = io.WriteString(conn, `{"event": "NIC_RX_FILTER_CHANGED", "data": {}}`+"\r\n")
+		if err != nil {
+			return err
+		}
+		response := `{"return": {}}`
+		switch cmd.Execute {
+		case "qmp_capabilities":
+		case "send-key":
+			var keys []string
+			for _, k := range cmd.Arguments.Keys {
+				if k.Type != "qcode" {
+					return fmt.Errorf("unexpected key type %q", k.Type)
+				}
+				keys = append(keys, k.Data)
+			}
+			if !s.record(strings.Join(keys, "-"), time.Duration(cmd.Arguments.HoldTime)*time.Millisecond) {
+				response = `{"error": {"class": "GenericError", "desc": "rejected"}}`
+			}
+		default:
+			response = `{"error": {"class": "CommandNotFound", "desc": "unknown command"}}`
+		}
+		_, err = io.WriteString(conn, response+"\r\n")
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *qemuServer) serveHMP(conn net.Conn) error {
+	_, err := io.WriteString(conn, "QEMU 8.2.0 monitor - type 'help' for more information\r\n"+hmpPrompt)
+	if err != nil {
+		return err
+	}
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		command := strings.TrimSpace(line)
+		// the monitor echoes the command
+		output := command + "\r\n"
+
+		var keys string
+		var ms int64
+		_, err = fmt.Sscanf(command, "sendkey %s %d", &keys, &ms)
+		if err != nil {
+			output += "unknown command: '" + command + "'\r\n"
+		} else if !s.record(keys, time.Duration(ms)*time.Millisecond) {
+			output += "unknown key: '" + keys + "'\r\n"
+		}
+		_, err = io.WriteString(conn, output+hmpPrompt)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// newQEMUServer starts a monitor that accepts a single connection.
+func newQEMUServer(t *testing.T, network string, hmp bool) (*qemuServer, string) {
+	t.Helper()
+	address := "127.0.0.1:0"
+	if network == "unix" {
+		address = filepath.Join(t.TempDir(), "qmp.sock")
+	}
+	l, err := net.Listen(network, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	s := &qemuServer{hmp: hmp, done: make(chan struct{})}
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			close(s.done)
+			return
+		}
+		s.serve(conn)
+	}()
+	return s, l.Addr().String()
+}
+
+func newQEMUKBWrap(t *testing.T, network string, hmp bool, opts ...KBOpt) (*KBWrap, *qemuServer) {
+	t.Helper()
+	server, address := newQEMUServer(t, network, hmp)
+	b, err := DialQEMU(context.Background(), network, address, QEMUConfig{HMP: hmp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts = append([]KBOpt{
+		WithBackend(b),
+		WithKeyMap(KeyMapLinuxQuartz()),
+		KeystrokeDuration(0),
+		DelayAfter(0),
+	}, opts...)
+	k, err := NewKBWrapWithOptions(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k, server
+}
+
+func TestQEMUBackend(t *testing.T) {
+	for _, tc := range []struct {
+		network string
+		hmp     bool
+	}{
+		{"unix", false},
+		{"tcp", false},
+		{"tcp", true},
+	} {
+		t.Run(fmt.Sprintf("%s/hmp=%t", tc.network, tc.hmp), func(t *testing.T) {
+			k, server := newQEMUKBWrap(t, tc.network, tc.hmp, KeystrokeDuration(20*time.Millisecond))
+
+			err := errors.Join(
+				k.Type("Hi!"),
+				k.KeyDown(ShiftKeyCode(29)),
+				k.Type("a"),
+				k.KeyUp(ShiftKeyCode(29)),
+				k.Close(),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected := []string{"shift-h", "i", "shift-1", "shift-ctrl-a", "shift-ctrl"}
+			keys := server.Keys()
+			if server.err != nil {
+				t.Fatal(server.err)
+			}
+			if strings.Join(keys, ",") != strings.Join(expected, ",") {
+				t.Errorf("expected %v, got %v", expected, keys)
+			}
+			for i, hold := range server.hold[:3] {
+				if hold < 20*time.Millisecond {
+					t.Errorf("%s: expected the keystroke duration, got %s", keys[i], hold)
+				}
+			}
+		})
+	}
+}
+
+func TestQEMUBackendError(t *testing.T) {
+	for _, hmp := range []bool{false, true} {
+		t.Run(fmt.Sprintf("hmp=%t", hmp), func(t *testing.T) {
+			server, address := newQEMUServer(t, "tcp", hmp)
+			server.fail = "b"
+			b, err := DialQEMU(context.Background(), "tcp", address, QEMUConfig{HMP: hmp})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer b.Close()
+
+			err = errors.Join(b.Press(SimpleKeyCode(30)), b.Release(SimpleKeyCode(30)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = b.Release(SimpleKeyCode(48))
+			if !errors.Is(err, ErrQEMU) {
+				t.Errorf("expected ErrQEMU, got %v", err)
+			}
+			err = b.Press(SimpleKeyCode(1000))
+			if !errors.Is(err, ErrNoQCode) {
+				t.Errorf("expected ErrNoQCode, got %v", err)
+			}
+		})
+	}
+}
+
+func TestQEMUBackendKeysyms(t *testing.T) {
+	k, server := newQEMUKBWrap(t, "tcp", false, WithKeysymMap(nil), WithKeyMap(nil))
+
+	err := errors.Join(k.Press(KeyF5, ModCtrl), k.Chord("alt+tab"), k.Send("{Shift down}a{Shift up}"), k.Close())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"ctrl-f5", "alt-tab", "shift-a", "shift"}
+	if keys := server.Keys(); strings.Join(keys, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, got %v", expected, keys)
+	}
+}
@@ -0,0 +1,389 @@
+package sendkeys
+
+import (
+	"bufio"
+	"context"
+	"crypto/des"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+var (
+	// ErrRFBProtocol is returned when the VNC server does not speak a supported version of the RFB protocol.
+	ErrRFBProtocol = errors.New("rfb protocol error")
+	// ErrRFBAuthFailed is returned when the VNC server rejects the connection, e.g. due to a wrong password.
+	ErrRFBAuthFailed = errors.New("rfb authentication failed")
+	// ErrNoKeysym is returned when a key code cannot be translated into an X11 keysym.
+	ErrNoKeysym = errors.New("no keysym for key code")
+)
+
+// RFB security types
+const (
+	rfbSecurityInvalid = 0
+	rfbSecurityNone    = 1
+	rfbSecurityVNCAuth = 2
+)
+
+// rfbKeyEvent is the message type of the KeyEvent client message.
+const rfbKeyEvent = 4
+
+// RFBConfig configures the connection to a VNC server.
+type RFBConfig struct {
+	// Password is used in case the server requires VNC authentication.
+	// Only the first 8 characters are significant.
+	Password string
+	// Shared keeps other clients connected to the server.
+	Shared bool
+	// Keysym translates the key code of a KeyCode into an X11 keysym.
+	// Modifiers are sent as separate keys. Defaults to EvdevKeysym, which expects
+	// the key codes of KeyMapLinuxQuartz.
+	Keysym func(code int) (uint32, bool)
+}
+
+// RFBBackend is a Backend that sends key events to a VNC server using the
+// RFB protocol (RFC 6143). It does not depend on the focus of a local window.
+type RFBBackend struct {
+	conn   net.Conn
+	keysym func(code int) (uint32, bool)
+	name   string
+	width  int
+	height int
+
+	mu   sync.Mutex
+	done chan struct{}
+}
+
+// DialRFB connects to the VNC server at the given TCP address, e.g. "localhost:5900",
+// and performs the handshake.
+func DialRFB(ctx context.Context, addr string, cfg RFBConfig) (*RFBBackend, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	b, err := NewRFBBackend(ctx, conn, cfg)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+// NewRFBBackend performs the RFB handshake on an established connection.
+// The handshake is aborted when the context is cancelled.
+func NewRFBBackend(ctx context.Context, conn net.Conn, cfg RFBConfig) (*RFBBackend, error) {
+	b := &RFBBackend{
+		conn:   conn,
+		keysym: cfg.Keysym,
+		done:   make(chan struct{}),
+	}
+	if b.keysym == nil {
+		b.keysym = EvdevKeysym
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		// unblock reads and writes of the handshake
+		_ = conn.Close()
+	})
+	err := b.handshake(bufio.NewReader(conn), cfg)
+	if !stop() {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	go b.discard()
+	return b, nil
+}
+
+// handshake negotiates the protocol version and the security type and initializes the session.
+func (b *RFBBackend) handshake(r *bufio.Reader, cfg RFBConfig) error {
+	var version [12]byte
+	_, err := io.ReadFull(r, version[:])
+	if err != nil {
+		return err
+	}
+	var major, minor int
+	_, err = fmt.Sscanf(string(version[:]), "RFB %03d.%03d\n", &major, &minor)
+	if err != nil || major != 3 || minor < 3 {
+		return fmt.Errorf("%w: unsupported version %q", ErrRFBProtocol, version)
+	}
+	// 3.3 and 3.7 are the older versions, everything else is treated as 3.8
+	switch {
+	case minor >= 8:
+		minor = 8
+	case minor >= 7:
+		minor = 7
+	default:
+		minor = 3
+	}
+	_, err = fmt.Fprintf(b.conn, "RFB 003.%03d\n", minor)
+	if err != nil {
+		return err
+	}
+
+	security, err := b.negotiateSecurity(r, minor)
+	if err != nil {
+		return err
+	}
+	if security == rfbSecurityVNCAuth {
+		err = b.authenticate(r, cfg.Password)
+		if err != nil {
+			return err
+		}
+	}
+	if security == rfbSecurityVNCAuth || minor >= 8 {
+		err = b.securityResult(r, minor)
+		if err != nil {
+			return err
+		}
+	}
+
+	shared := byte(0)
+	if cfg.Shared {
+		shared = 1
+	}
+	_, err = b.conn.Write([]byte{shared})
+	if err != nil {
+		return err
+	}
+	return b.serverInit(r)
+}
+
+// negotiateSecurity selects the security type, VNC authentication is only used
+// in case the server does not allow connections without authentication.
+func (b *RFBBackend) negotiateSecurity(r *bufio.Reader, minor int) (byte, error) {
+	if minor == 3 {
+		// the server decides
+		var security uint32
+		err := binary.Read(r, binary.BigEndian, &security)
+		if err != nil {
+			return 0, err
+		}
+		switch security {
+		case rfbSecurityNone, rfbSecurityVNCAuth:
+			return byte(security), nil
+		case rfbSecurityInvalid:
+			return 0, rfbReason(r, ErrRFBAuthFailed)
+		default:
+			return 0, fmt.Errorf("%w: unsupported security type %d", ErrRFBProtocol, security)
+		}
+	}
+
+	n, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, rfbReason(r, ErrRFBAuthFailed)
+	}
+	types := make([]byte, n)
+	_, err = io.ReadFull(r, types)
+	if err != nil {
+		return 0, err
+	}
+	security := byte(rfbSecurityInvalid)
+	for _, t := range types {
+		if t == rfbSecurityNone {
+			security = t
+			break
+		}
+		if t == rfbSecurityVNCAuth {
+			security = t
+		}
+	}
+	if security == rfbSecurityInvalid {
+		return 0, fmt.Errorf("%w: unsupported security types %v", ErrRFBProtocol, types)
+	}
+	_, err = b.conn.Write([]byte{security})
+	return security, err
+}
+
+// authenticate answers the challenge of the VNC authentication.
+func (b *RFBBackend) authenticate(r *bufio.Reader, password string) error {
+	var challenge [16]byte
+	_, err := io.ReadFull(r, challenge[:])
+	if err != nil {
+		return err
+	}
+	response, err := vncAuthResponse(password, challenge)
+	if err != nil {
+		return err
+	}
+	_, err = b.conn.Write(response[:])
+	return err
+}
+
+// vncAuthResponse encrypts the challenge with DES, the key is the password
+// with the bits of every byte in reverse order.
+func vncAuthResponse(password string, challenge [16]byte) ([16]byte, error) {
+	var (
+		key      [8]byte
+		response [16]byte
+	)
+	copy(key[:], password)
+	for i, k := range key {
+		var reversed byte
+		for bit := 0; bit < 8; bit++ {
+			if k&(1<<bit) != 0 {
+				reversed |= 0x80 >> bit
+			}
+		}
+		key[i] = reversed
+	}
+	cipher, err := des.NewCipher(key[:])
+	if err != nil {
+		return response, err
+	}
+	cipher.Encrypt(response[:8], challenge[:8])
+	cipher.Encrypt(response[8:], challenge[8:])
+	return response, nil
+}
+
+// securityResult reads the result of the security handshake.
+func (b *RFBBackend) securityResult(r *bufio.Reader, minor int) error {
+	var result uint32
+	err := binary.Read(r, binary.BigEndian, &result)
+	if err != nil {
+		return err
+	}
+	if result == 0 {
+		return nil
+	}
+	if minor >= 8 {
+		return rfbReason(r, ErrRFBAuthFailed)
+	}
+	return ErrRFBAuthFailed
+}
+
+// serverInit reads the size of the framebuffer and the name of the desktop.
+func (b *RFBBackend) serverInit(r *bufio.Reader) error {
+	var init struct {
+		Width, Height uint16
+		PixelFormat   [16]byte
+		NameLength    uint32
+	}
+	err := binary.Read(r, binary.BigEndian, &init)
+	if err != nil {
+		return err
+	}
+	if init.NameLength > 1<<16 {
+		return fmt.Errorf("%w: desktop name of %d bytes", ErrRFBProtocol, init.NameLength)
+	}
+	name := make([]byte, init.NameLength)
+	_, err = io.ReadFull(r, name)
+	if err != nil {
+		return err
+	}
+	b.width, b.height = int(init.Width), int(init.Height)
+	b.name = string(name)
+	return nil
+}
+
+// rfbReason reads the reason string of a failed handshake.
+func rfbReason(r io.Reader, err error) error {
+	var n uint32
+	if binary.Read(r, binary.BigEndian, &n) != nil || n > 1<<16 {
+		return err
+	}
+	reason := make([]byte, n)
+	if _, rerr := io.ReadFull(r, reason); rerr != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %s", err, reason)
+}
+
+// discard reads and drops all server messages, as no framebuffer updates are requested.
+func (b *RFBBackend) discard() {
+	defer close(b.done)
+	_, _ = io.Copy(io.Discard, b.conn)
+}
+
+// Name returns the name of the remote desktop.
+func (b *RFBBackend) Name() string {
+	return b.name
+}
+
+// Size returns the size of the remote framebuffer.
+func (b *RFBBackend) Size() (width, height int) {
+	return b.width, b.height
+}
+
+// keyEvent sends a KeyEvent message.
+func (b *RFBBackend) keyEvent(down bool, keysym uint32) error {
+	msg := [8]byte{0: rfbKeyEvent}
+	if down {
+		msg[1] = 1
+	}
+	binary.BigEndian.PutUint32(msg[4:], keysym)
+	_, err := b.conn.Write(msg[:])
+	return err
+}
+
+// keysyms returns the keysyms of the modifiers followed by the keysym of the key.
+// The keysym of the key code takes precedence over the translated key code.
+func (b *RFBBackend) keysyms(key KeyCode) ([]uint32, error) {
+	if key.ModifiersOnly() {
+		return modifierKeysyms(key), nil
+	}
+	ks, ok := key.Keysym, key.Keysym != 0
+	if !ok {
+		ks, ok = b.keysym(key.Code)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrNoKeysym, key.Code)
+	}
+	return append(modifierKeysyms(key), ks), nil
+}
+
+// Press presses the modifiers that are set in key followed by the key itself.
+func (b *RFBBackend) Press(key KeyCode) error {
+	keysyms, err := b.keysyms(key)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, ks := range keysyms {
+		err = b.keyEvent(true, ks)
+		if err != nil {
+			// release the modifiers that were pressed already
+			errs := []error{err}
+			for j := i - 1; j >= 0; j-- {
+				errs = append(errs, b.keyEvent(false, keysyms[j]))
+			}
+			return errors.Join(errs...)
+		}
+	}
+	return nil
+}
+
+// Release releases the key followed by the modifiers that are set in key.
+func (b *RFBBackend) Release(key KeyCode) error {
+	keysyms, err := b.keysyms(key)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// release the modifiers even if the release of the key fails
+	var errs []error
+	for i := len(keysyms) - 1; i >= 0; i-- {
+		errs = append(errs, b.keyEvent(false, keysyms[i]))
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes the connection to the VNC server.
+func (b *RFBBackend) Close() error {
+	err := b.conn.Close()
+	<-b.done
+	return err
+}
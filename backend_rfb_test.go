@@ -0,0 +1,320 @@
+package sendkeys
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+)
+
+// rfbKey is a KeyEvent message received by the rfbServer.
+type rfbKey struct {
+	Down   bool
+	Keysym uint32
+}
+
+func (k rfbKey) String() string {
+	if k.Down {
+		return fmt.Sprintf("down %#x", k.Keysym)
+	}
+	return fmt.Sprintf("up %#x", k.Keysym)
+}
+
+// rfbServer is a minimal VNC server that records the received key events.
+type rfbServer struct {
+	version  string // e.g. "RFB 003.008\n"
+	password string // enables VNC authentication
+
+	mu   sync.Mutex
+	keys []rfbKey
+	err  error
+	done chan struct{}
+}
+
+func (s *rfbServer) Keys() []rfbKey {
+	<-s.done
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keys
+}
+
+func (s *rfbServer) serve(conn net.Conn) {
+	defer close(s.done)
+	defer conn.Close()
+
+	err := s.handshake(conn)
+	if err != nil {
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+		return
+	}
+	for {
+		var msg [8]byte
+		_, err := io.ReadFull(conn, msg[:])
+		if err != nil {
+			return
+		}
+		if msg[0] != rfbKeyEvent {
+			s.err = fmt.Errorf("unexpected message type %d", msg[0])
+			return
+		}
+		s.mu.Lock()
+		s.keys = append(s.keys, rfbKey{Down: msg[1] == 1, Keysym: binary.BigEndian.Uint32(msg[4:])})
+		s.mu.Unlock()
+	}
+}
+
+func (s *rfbServer) handshake(conn net.Conn) error {
+	_, err := io.WriteString(conn, s.version)
+	if err != nil {
+		return err
+	}
+	var version [12]byte
+	_, err = io.ReadFull(conn, version[:])
+	if err != nil {
+		return err
+	}
+	if string(version[:]) != s.version {
+		return fmt.Errorf("unexpected version %q", version)
+	}
+
+	security := byte(rfbSecurityNone)
+	if s.password != "" {
+		security = rfbSecurityVNCAuth
+	}
+	if s.version == "RFB 003.003\n" {
+		err = binary.Write(conn, binary.BigEndian, uint32(security))
+	} else {
+		_, err = conn.Write([]byte{1, security})
+		if err == nil {
+			_, err = io.ReadFull(conn, version[:1])
+		}
+		if err == nil && version[0] != security {
+			err = fmt.Errorf("unexpected security type %d", version[0])
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if security == rfbSecurityVNCAuth {
+		challenge := [16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+		_, err = conn.Write(challenge[:])
+		if err != nil {
+			return err
+		}
+		var response [16]byte
+		_, err = io.ReadFull(conn, response[:])
+		if err != nil {
+			return err
+		}
+		expected, _ := vncAuthResponse(s.password, challenge)
+		if response != expected {
+			reason := "wrong password"
+			_ = binary.Write(conn, binary.BigEndian, uint32(1))
+			_ = binary.Write(conn, binary.BigEndian, uint32(len(reason)))
+			_, _ = io.WriteString(conn, reason)
+			return errors.New(reason)
+		}
+	}
+	if security == rfbSecurityVNCAuth || s.version == "RFB 003.008\n" {
+		err = binary.Write(conn, binary.BigEndian, uint32(0))
+		if err != nil {
+			return err
+		}
+	}
+
+	var shared [1]byte
+	_, err = io.ReadFull(conn, shared[:])
+	if err != nil {
+		return err
+	}
+	var init bytes.Buffer
+	_ = binary.Write(&init, binary.BigEndian, []uint16{1024, 768})
+	init.Write(make([]byte, 16))
+	_ = binary.Write(&init, binary.BigEndian, uint32(len("test")))
+	init.WriteString("test")
+	_, err = conn.Write(init.Bytes())
+	return err
+}
+
+// newRFBServer starts a server that accepts a single connection on a local TCP port.
+func newRFBServer(t *testing.T, version, password string) (*rfbServer, string) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	s := &rfbServer{version: version, password: password, done: make(chan struct{})}
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			close(s.done)
+			return
+		}
+		s.serve(conn)
+	}()
+	return s, l.Addr().String()
+}
+
+func TestRFBBackend(t *testing.T) {
+	for _, version := range []string{"RFB 003.003\n", "RFB 003.007\n", "RFB 003.008\n"} {
+		for _, password := range []string{"", "secret"} {
+			t.Run(fmt.Sprintf("%q/%q", version, password), func(t *testing.T) {
+				server, addr := newRFBServer(t, version, password)
+				b, err := DialRFB(context.Background(), addr, RFBConfig{Password: password})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if w, h := b.Size(); b.Name() != "test" || w != 1024 || h != 768 {
+					t.Errorf("unexpected desktop %q %dx%d", b.Name(), w, h)
+				}
+
+				k, err := NewKBWrapWithOptions(
+					WithBackend(b),
+					WithKeyMap(KeyMapLinuxQuartz()),
+					KeystrokeDuration(0),
+					DelayAfter(0),
+				)
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = k.Type("Hi")
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = k.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				expected := []rfbKey{
+					{true, 0xffe1}, {true, 'h'}, {false, 'h'}, {false, 0xffe1},
+					{true, 'i'}, {false, 'i'},
+				}
+				keys := server.Keys()
+				if server.err != nil {
+					t.Fatal(server.err)
+				}
+				if fmt.Sprint(keys) != fmt.Sprint(expected) {
+					t.Errorf("expected %v, got %v", expected, keys)
+				}
+			})
+		}
+	}
+}
+
+func TestRFBBackendWrongPassword(t *testing.T) {
+	_, addr := newRFBServer(t, "RFB 003.008\n", "secret")
+	_, err := DialRFB(context.Background(), addr, RFBConfig{Password: "wrong"})
+	if !errors.Is(err, ErrRFBAuthFailed) {
+		t.Fatalf("expected ErrRFBAuthFailed, got %v", err)
+	}
+	if err.Error() != "rfb authentication failed: wrong password" {
+		t.Errorf("unexpected message: %v", err)
+	}
+}
+
+func TestRFBBackendUnsupportedVersion(t *testing.T) {
+	_, addr := newRFBServer(t, "RFB 004.001\n", "")
+	_, err := DialRFB(context.Background(), addr, RFBConfig{})
+	if !errors.Is(err, ErrRFBProtocol) {
+		t.Fatalf("expected ErrRFBProtocol, got %v", err)
+	}
+}
+
+func TestRFBBackendModifiers(t *testing.T) {
+	server, addr := newRFBServer(t, "RFB 003.008\n", "")
+	b, err := DialRFB(context.Background(), addr, RFBConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := KeyCode{Code: 46, ModifierCTRL: true, ModifierALT: true, ModifierSide: SideRight}
+	err = errors.Join(b.Press(key), b.Release(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = b.Press(SimpleKeyCode(1000))
+	if !errors.Is(err, ErrNoKeysym) {
+		t.Errorf("expected ErrNoKeysym, got %v", err)
+	}
+	_ = b.Close()
+
+	expected := []rfbKey{
+		{true, 0xffe4}, {true, 0xffea}, {true, 'c'},
+		{false, 'c'}, {false, 0xffea}, {false, 0xffe4},
+	}
+	if keys := server.Keys(); fmt.Sprint(keys) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, keys)
+	}
+}
+
+// rejectingConn fails to write the key events of a keysym.
+type rejectingConn struct {
+	net.Conn
+	keysym uint32
+}
+
+func (c *rejectingConn) Write(b []byte) (int, error) {
+	if len(b) == 8 && b[0] == rfbKeyEvent && binary.BigEndian.Uint32(b[4:]) == c.keysym {
+		return 0, errBroken
+	}
+	return c.Conn.Write(b)
+}
+
+func TestRFBBackendPressError(t *testing.T) {
+	server, addr := newRFBServer(t, "RFB 003.008\n", "")
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewRFBBackend(context.Background(), &rejectingConn{Conn: conn, keysym: 'c'}, RFBConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := KeyCode{Code: 46, ModifierCTRL: true, ModifierSHIFT: true}
+	err = b.Press(key)
+	if !errors.Is(err, errBroken) {
+		t.Errorf("expected errBroken, got %v", err)
+	}
+	err = errors.Join(b.Press(ShiftKeyCode(30)), b.Release(key), b.Release(ShiftKeyCode(30)))
+	if !errors.Is(err, errBroken) {
+		t.Errorf("expected errBroken, got %v", err)
+	}
+	_ = b.Close()
+
+	// the modifiers that were pressed before the error are released,
+	// as well as the modifiers of a key whose release fails
+	expected := []rfbKey{
+		{true, 0xffe3}, {true, 0xffe1},
+		{false, 0xffe1}, {false, 0xffe3},
+		{true, 0xffe1}, {true, 'a'},
+		{false, 0xffe1}, {false, 0xffe3},
+		{false, 'a'}, {false, 0xffe1},
+	}
+	if keys := server.Keys(); fmt.Sprint(keys) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, keys)
+	}
+}
+
+func TestRFBBackendContext(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := NewRFBBackend(ctx, client, RFBConfig{})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,515 @@
+package sendkeys
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+var (
+	// ErrX11 is returned when the X server reports an error or speaks an unexpected protocol.
+	ErrX11 = errors.New("x11 error")
+	// ErrX11Refused is returned when the X server refuses the connection, e.g. due to missing authorization.
+	ErrX11Refused = errors.New("x11 connection refused")
+	// ErrNoXTEST is returned when the X server does not support the XTEST extension.
+	ErrNoXTEST = errors.New("x11 server does not support XTEST")
+	// ErrNoKeycode is returned when the X server has no key code for a key.
+	ErrNoKeycode = errors.New("no x11 key code for key")
+)
+
+// X11 request opcodes
+const (
+	x11GetInputFocus      = 43
+	x11QueryExtension     = 98
+	x11GetKeyboardMapping = 101
+	xtestFakeInput        = 2
+)
+
+// X11 event types of the XTEST FakeInput request
+const (
+	x11KeyPress   = 2
+	x11KeyRelease = 3
+)
+
+// X11Config configures the connection to an X server.
+type X11Config struct {
+	// Display is the X display, e.g. ":0" or "localhost:10.0". Defaults to $DISPLAY.
+	Display string
+	// AuthName and AuthData are the authorization protocol, e.g. "MIT-MAGIC-COOKIE-1",
+	// and its data. DialX11 defaults to the entry of the display in $XAUTHORITY
+	// or ~/.Xauthority.
+	AuthName string
+	AuthData []byte
+}
+
+// X11Backend is a Backend that injects key events into an X server, e.g. Xvfb,
+// using the XTEST extension. The events take effect immediately, so there is no
+// startup delay like with the default backend.
+//
+// The key codes are the key codes of the X server, KeyMap returns the KeyMap of
+// its active layout. Keysyms, see WithKeysymMap, are translated with the same
+// keyboard mapping.
+type X11Backend struct {
+	conn  net.Conn
+	r     *bufio.Reader
+	xtest byte // major opcode of the XTEST extension
+
+	minKeycode, maxKeycode int
+	keyMap                 KeyMap
+	keysyms                map[uint32]KeyCode
+
+	mu  sync.Mutex
+	seq uint16
+}
+
+// DialX11 connects to the X server of the display and performs the handshake.
+func DialX11(ctx context.Context, cfg X11Config) (*X11Backend, error) {
+	if cfg.Display == "" {
+		cfg.Display = os.Getenv("DISPLAY")
+	}
+	network, address, number, err := parseX11Display(cfg.Display)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.AuthName == "" {
+		cfg.AuthName, cfg.AuthData = xauthority(network, number)
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	b, err := NewX11Backend(ctx, conn, cfg)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+// parseX11Display returns the address of the X server and the display number
+// of a display like ":0", "unix:0", "localhost:10.0" or "/tmp/launch-x/org.xquartz:0".
+func parseX11Display(display string) (network, address, number string, err error) {
+	i := strings.LastIndexByte(display, ':')
+	if i < 0 {
+		return "", "", "", fmt.Errorf("%w: invalid display %q", ErrX11, display)
+	}
+	host, number := display[:i], display[i+1:]
+	number, _, _ = strings.Cut(number, ".")
+	n, err := strconv.Atoi(number)
+	if err != nil || n < 0 {
+		return "", "", "", fmt.Errorf("%w: invalid display %q", ErrX11, display)
+	}
+
+	switch {
+	case host == "" || host == "unix":
+		return "unix", "/tmp/.X11-unix/X" + number, number, nil
+	case strings.HasPrefix(host, "/"):
+		return "unix", host + ":" + number, number, nil
+	default:
+		host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+		return "tcp", net.JoinHostPort(host, strconv.Itoa(6000+n)), number, nil
+	}
+}
+
+// xauthority returns the authorization of the display from the Xauthority file.
+// No authorization is returned in case the file cannot be read.
+func xauthority(network, number string) (name string, data []byte) {
+	path := os.Getenv("XAUTHORITY")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", nil
+		}
+		path = filepath.Join(home, ".Xauthority")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", nil
+	}
+	defer f.Close()
+
+	hostname, _ := os.Hostname()
+	r := bufio.NewReader(f)
+	readString := func() ([]byte, error) {
+		var n uint16
+		err := binary.Read(r, binary.BigEndian, &n)
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, n)
+		_, err = io.ReadFull(r, b)
+		return b, err
+	}
+	for {
+		var family uint16
+		err := binary.Read(r, binary.BigEndian, &family)
+		if err != nil {
+			return "", nil
+		}
+		var fields [4][]byte // address, display number, name and data
+		for i := range fields {
+			fields[i], err = readString()
+			if err != nil {
+				return "", nil
+			}
+		}
+
+		const (
+			familyLocal = 256
+			familyWild  = 65535
+		)
+		host := family == familyWild || family == familyLocal && network == "unix" && string(fields[0]) == hostname
+		if host && (len(fields[1]) == 0 || string(fields[1]) == number) {
+			return string(fields[2]), fields[3]
+		}
+	}
+}
+
+// pad4 returns the number of bytes that pad n bytes to a multiple of four.
+func pad4(n int) int {
+	return (4 - n%4) % 4
+}
+
+// NewX11Backend performs the handshake on an established connection, checks for the
+// XTEST extension and reads the keyboard mapping. The handshake is aborted when the
+// context is cancelled.
+func NewX11Backend(ctx context.Context, conn net.Conn, cfg X11Config) (*X11Backend, error) {
+	b := &X11Backend{
+		conn: conn,
+		r:    bufio.NewReader(conn),
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		// unblock reads and writes of the handshake
+		_ = conn.Close()
+	})
+	err := b.handshake(cfg)
+	if !stop() {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// handshake sets up the connection and reads the keyboard mapping.
+func (b *X11Backend) handshake(cfg X11Config) error {
+	err := b.setup(cfg)
+	if err != nil {
+		return err
+	}
+	err = b.queryXTEST()
+	if err != nil {
+		return err
+	}
+	return b.keyboardMapping()
+}
+
+// setup sends the connection setup in little endian byte order and reads the range of key codes.
+func (b *X11Backend) setup(cfg X11Config) error {
+	name, data := []byte(cfg.AuthName), cfg.AuthData
+	msg := make([]byte, 12, 12+len(name)+pad4(len(name))+len(data)+pad4(len(data)))
+	msg[0] = 'l'
+	binary.LittleEndian.PutUint16(msg[2:], 11) // protocol version 11.0
+	binary.LittleEndian.PutUint16(msg[6:], uint16(len(name)))
+	binary.LittleEndian.PutUint16(msg[8:], uint16(len(data)))
+	msg = append(msg, name...)
+	msg = append(msg, make([]byte, pad4(len(name)))...)
+	msg = append(msg, data...)
+	msg = append(msg, make([]byte, pad4(len(data)))...)
+	_, err := b.conn.Write(msg)
+	if err != nil {
+		return err
+	}
+
+	var header [8]byte
+	_, err = io.ReadFull(b.r, header[:])
+	if err != nil {
+		return err
+	}
+	reply := make([]byte, 4*int(binary.LittleEndian.Uint16(header[6:])))
+	_, err = io.ReadFull(b.r, reply)
+	if err != nil {
+		return err
+	}
+
+	switch header[0] {
+	case 0: // failed
+		n := min(int(header[1]), len(reply))
+		return fmt.Errorf("%w: %s", ErrX11Refused, strings.TrimSpace(string(reply[:n])))
+	case 2: // further authentication required
+		return fmt.Errorf("%w: %s", ErrX11Refused, strings.TrimRight(string(reply), "\x00 \n"))
+	case 1:
+	default:
+		return fmt.Errorf("%w: unexpected setup status %d", ErrX11, header[0])
+	}
+	if len(reply) < 32 {
+		return fmt.Errorf("%w: short setup reply", ErrX11)
+	}
+	b.minKeycode, b.maxKeycode = int(reply[26]), int(reply[27])
+	if b.minKeycode < 8 || b.maxKeycode < b.minKeycode {
+		return fmt.Errorf("%w: invalid key code range %d-%d", ErrX11, b.minKeycode, b.maxKeycode)
+	}
+	return nil
+}
+
+// request sends a request and returns its sequence number.
+func (b *X11Backend) request(msg []byte) (uint16, error) {
+	_, err := b.conn.Write(msg)
+	if err != nil {
+		return 0, err
+	}
+	b.seq++
+	return b.seq, nil
+}
+
+// reply reads the reply of the request with the given sequence number.
+// Events and replies of other requests are skipped, errors are returned.
+func (b *X11Backend) reply(seq uint16) ([]byte, error) {
+	for {
+		msg := make([]byte, 32)
+		_, err := io.ReadFull(b.r, msg)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case msg[0] == 0:
+			return nil, fmt.Errorf("%w: error %d of request %d.%d",
+				ErrX11, msg[1], msg[10], binary.LittleEndian.Uint16(msg[8:]))
+		case msg[0] == 1, msg[0]&0x7f == 35: // replies and generic events
+			extra := make([]byte, 4*int(binary.LittleEndian.Uint32(msg[4:])))
+			_, err = io.ReadFull(b.r, extra)
+			if err != nil {
+				return nil, err
+			}
+			if msg[0] == 1 && binary.LittleEndian.Uint16(msg[2:]) == seq {
+				return append(msg, extra...), nil
+			}
+		}
+	}
+}
+
+// queryXTEST reads the major opcode of the XTEST extension.
+func (b *X11Backend) queryXTEST() error {
+	const name = "XTEST"
+	msg := make([]byte, 8, 8+len(name)+pad4(len(name)))
+	msg[0] = x11QueryExtension
+	binary.LittleEndian.PutUint16(msg[2:], uint16(cap(msg)/4))
+	binary.LittleEndian.PutUint16(msg[4:], uint16(len(name)))
+	msg = append(msg, name...)
+	msg = append(msg, make([]byte, pad4(len(name)))...)
+	seq, err := b.request(msg)
+	if err != nil {
+		return err
+	}
+	reply, err := b.reply(seq)
+	if err != nil {
+		return err
+	}
+	if reply[8] == 0 {
+		return ErrNoXTEST
+	}
+	b.xtest = reply[9]
+	return nil
+}
+
+// keyboardMapping reads the keysyms of all key codes and creates the KeyMap
+// as well as the translation of keysyms.
+func (b *X11Backend) keyboardMapping() error {
+	count := b.maxKeycode - b.minKeycode + 1
+	msg := []byte{x11GetKeyboardMapping, 0, 2, 0, byte(b.minKeycode), byte(count), 0, 0}
+	seq, err := b.request(msg)
+	if err != nil {
+		return err
+	}
+	reply, err := b.reply(seq)
+	if err != nil {
+		return err
+	}
+	perKeycode := int(reply[1])
+	if len(reply) < 32+4*count*perKeycode {
+		return fmt.Errorf("%w: short keyboard mapping", ErrX11)
+	}
+
+	mapping := make([][]uint32, count)
+	for i := range mapping {
+		mapping[i] = make([]uint32, perKeycode)
+		for j := range mapping[i] {
+			mapping[i][j] = binary.LittleEndian.Uint32(reply[32+4*(i*perKeycode+j):])
+		}
+	}
+	b.keyMap, b.keysyms = x11KeyMap(b.minKeycode, mapping)
+	return nil
+}
+
+// x11Levels returns the keysyms of the first four shift levels of the first group.
+// Servers with the XKB extension report the third and fourth level in the fifth
+// and sixth column.
+func x11Levels(keysyms []uint32) [4]uint32 {
+	var levels [4]uint32
+	for level, column := range []int{0, 1, 4, 5} {
+		if column < len(keysyms) {
+			levels[level] = keysyms[column]
+		}
+	}
+	// a single alphabetic keysym is used for both cases
+	if levels[1] == 0 {
+		if r, ok := keysymToRune(levels[0]); ok && unicode.IsLower(r) {
+			levels[1], _ = RuneKeysym(unicode.ToUpper(r))
+		}
+	}
+	return levels
+}
+
+// x11KeyMap creates the KeyMap of a keyboard mapping and the key codes of all keysyms,
+// lower levels and lower key codes take precedence.
+func x11KeyMap(minKeycode int, mapping [][]uint32) (KeyMap, map[uint32]KeyCode) {
+	keyMap := KeyMap{}
+	keysyms := map[uint32]KeyCode{}
+	for level := 0; level < 4; level++ {
+		for i, column := range mapping {
+			ks := x11Levels(column)[level]
+			if ks == 0 {
+				continue
+			}
+			if _, ok := keysyms[ks]; ok {
+				continue
+			}
+			key := KeyCode{
+				Code:          minKeycode + i,
+				ModifierSHIFT: level%2 == 1,
+				ModifierALTGR: level >= 2,
+			}
+			keysyms[ks] = key
+
+			r, ok := xkbControlKeysyms[ks]
+			if !ok {
+				r, ok = keysymToRune(ks)
+			}
+			if !ok || r < 0x20 && r != '\n' {
+				continue
+			}
+			if _, ok := keyMap[r]; !ok {
+				keyMap[r] = key
+			}
+		}
+	}
+	return keyMap, keysyms
+}
+
+// KeyMap returns the KeyMap of the active layout of the X server.
+func (b *X11Backend) KeyMap() KeyMap {
+	keyMap := make(KeyMap, len(b.keyMap))
+	for r, key := range b.keyMap {
+		keyMap[r] = key
+	}
+	return keyMap
+}
+
+// keycodes returns the key codes of the modifiers followed by the key code of the key, if any.
+// The keysym of the key code takes precedence over its key code.
+func (b *X11Backend) keycodes(key KeyCode) ([]byte, error) {
+	if key.Code == 0 && key.Keysym != 0 {
+		k, ok := b.keysyms[key.Keysym]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrNoKeycode, key)
+		}
+		key.Code = k.Code
+		key.ModifierSHIFT = key.ModifierSHIFT || k.ModifierSHIFT
+		key.ModifierALTGR = key.ModifierALTGR || k.ModifierALTGR
+	}
+	if !key.ModifiersOnly() && (key.Code < b.minKeycode || key.Code > b.maxKeycode) {
+		return nil, fmt.Errorf("%w: %s", ErrNoKeycode, key)
+	}
+
+	var codes []byte
+	for _, ks := range modifierKeysyms(key) {
+		k, ok := b.keysyms[ks]
+		if !ok {
+			return nil, fmt.Errorf("%w: modifier keysym %#x", ErrNoKeycode, ks)
+		}
+		codes = append(codes, byte(k.Code))
+	}
+	if key.ModifiersOnly() {
+		return codes, nil
+	}
+	return append(codes, byte(key.Code)), nil
+}
+
+// fakeInput sends the key events and waits until the server has processed them.
+func (b *X11Backend) fakeInput(eventType byte, codes []byte) error {
+	msg := make([]byte, 0, 36*len(codes)+4)
+	for _, code := range codes {
+		event := [36]byte{0: b.xtest, 1: xtestFakeInput, 2: 9, 4: eventType, 5: code}
+		msg = append(msg, event[:]...)
+	}
+	// GetInputFocus has a reply, which is sent after all errors of the events
+	msg = append(msg, x11GetInputFocus, 0, 1, 0)
+	_, err := b.conn.Write(msg)
+	if err != nil {
+		return err
+	}
+	b.seq += uint16(len(codes)) + 1
+	_, err = b.reply(b.seq)
+	return err
+}
+
+// Press presses the modifiers that are set in key followed by the key itself.
+func (b *X11Backend) Press(key KeyCode) error {
+	codes, err := b.keycodes(key)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	err = b.fakeInput(x11KeyPress, codes)
+	if err != nil {
+		// the error does not tell which event was rejected,
+		// so release the modifiers that may have been pressed already
+		mods := codes
+		if !key.ModifiersOnly() {
+			mods = codes[:len(codes)-1]
+		}
+		if len(mods) > 0 {
+			mods = slices.Clone(mods)
+			slices.Reverse(mods)
+			err = errors.Join(err, b.fakeInput(x11KeyRelease, mods))
+		}
+	}
+	return err
+}
+
+// Release releases the key followed by the modifiers that are set in key.
+func (b *X11Backend) Release(key KeyCode) error {
+	codes, err := b.keycodes(key)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, j := 0, len(codes)-1; i < j; i, j = i+1, j-1 {
+		codes[i], codes[j] = codes[j], codes[i]
+	}
+	return b.fakeInput(x11KeyRelease, codes)
+}
+
+// Close closes the connection to the X server.
+func (b *X11Backend) Close() error {
+	return b.conn.Close()
+}
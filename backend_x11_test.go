@@ -0,0 +1,416 @@
+package sendkeys
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// x11TestMapping is the keyboard mapping of the x11Server in the column
+// order of a server with the XKB extension.
+var x11TestMapping = map[byte][]uint32{
+	10:  {'1', '!', '1', '!'},
+	23:  {0xff09, 0xfe20, 0xff09, 0xfe20},          // Tab, ISO_Left_Tab
+	24:  {'q', 'Q', 'q', 'Q', '@', 0},              // q with @ on the third level
+	26:  {'e', 'E', 'e', 'E', 0x20ac, 0},           // e with EuroSign on the third level
+	29:  {'z', 'Z', 'z', 'Z', 0x8fb, 0xa5},         // z like on a german keyboard
+	36:  {0xff0d, 0, 0xff0d},                       // Return
+	37:  {0xffe3, 0, 0xffe3},                       // Control_L
+	38:  {'a', 'A', 'a', 'A', 0xe6, 0xc6},          // a, A, ae, AE
+	50:  {0xffe1, 0, 0xffe1},                       // Shift_L
+	52:  {'y'},                                     // a single lower case keysym
+	62:  {0xffe2, 0, 0xffe2},                       // Shift_R
+	64:  {0xffe9, 0xffe7, 0xffe9, 0xffe7},          // Alt_L, Meta_L
+	65:  {' ', 0, ' '},                             // space
+	71:  {0xffc2, 0, 0xffc2},                       // F5
+	92:  {0xfe03, 0, 0xfe03},                       // ISO_Level3_Shift
+	133: {0xffeb, 0, 0xffeb},                       // Super_L
+	200: {'a', 'A'},                                // duplicate of a key code with a lower key code
+	201: {0xfe03, 0, 0xfe03, 0, 0xfe03, 0xfe03},    // second ISO_Level3_Shift
+	202: {0xe4, 0xc4, 0xe4, 0xc4, 0x1001e9e, 0xdf}, // adiaeresis, ß on the fourth level
+}
+
+// x11Event is a key event received by the x11Server.
+type x11Event struct {
+	Press   bool
+	Keycode byte
+}
+
+func (e x11Event) String() string {
+	if e.Press {
+		return fmt.Sprintf("press %d", e.Keycode)
+	}
+	return fmt.Sprintf("release %d", e.Keycode)
+}
+
+// x11Server is a minimal little endian X server with the XTEST extension
+// that records the received key events.
+type x11Server struct {
+	auth    []byte // required MIT-MAGIC-COOKIE-1
+	noXTEST bool
+	fail    byte // key code that is rejected with a Value error
+
+	mu     sync.Mutex
+	events []x11Event
+	err    error
+	done   chan struct{}
+}
+
+func (s *x11Server) Events() []x11Event {
+	<-s.done
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.events
+}
+
+func (s *x11Server) serve(conn net.Conn) {
+	defer close(s.done)
+	defer conn.Close()
+
+	err := s.setup(conn)
+	if err != nil {
+		s.err = err
+		return
+	}
+	var seq uint16
+	for {
+		var header [4]byte
+		_, err := io.ReadFull(conn, header[:])
+		if err != nil {
+			return
+		}
+		body := make([]byte, 4*int(binary.LittleEndian.Uint16(header[2:]))-4)
+		_, err = io.ReadFull(conn, body)
+		if err != nil {
+			return
+		}
+		seq++
+
+		reply := make([]byte, 32)
+		reply[0] = 1
+		binary.LittleEndian.PutUint16(reply[2:], seq)
+		switch header[0] {
+		case x11QueryExtension:
+			name := string(body[4 : 4+binary.LittleEndian.Uint16(body)])
+			if name == "XTEST" && !s.noXTEST {
+				reply[8], reply[9] = 1, 132
+			}
+		case x11GetKeyboardMapping:
+			first, count := body[0], int(body[1])
+			const perKeycode = 6
+			reply[1] = perKeycode
+			binary.LittleEndian.PutUint32(reply[4:], uint32(count*perKeycode))
+			for i := 0; i < count; i++ {
+				keysyms := make([]uint32, perKeycode)
+				copy(keysyms, x11TestMapping[first+byte(i)])
+				for _, ks := range keysyms {
+					reply = binary.LittleEndian.AppendUint32(reply, ks)
+				}
+			}
+		case x11GetInputFocus:
+		case 132:
+			if header[1] != xtestFakeInput {
+				s.err = fmt.Errorf("unexpected XTEST request %d", header[1])
+				return
+			}
+			code := body[1]
+			if code == s.fail {
+				// Value error
+				reply = make([]byte, 32)
+				reply[1] = 2
+				binary.LittleEndian.PutUint16(reply[2:], seq)
+				binary.LittleEndian.PutUint16(reply[8:], xtestFakeInput)
+				reply[10] = 132
+			} else {
+				s.mu.Lock()
+				s.events = append(s.events, x11Event{Press: body[0] == x11KeyPress, Keycode: code})
+				s.mu.Unlock()
+				// FakeInput has no reply, but events may be sent at any time
+				reply = make([]byte, 32)
+				reply[0] = 34 // MappingNotify
+			}
+		default:
+			s.err = fmt.Errorf("unexpected request %d", header[0])
+			return
+		}
+		_, err = conn.Write(reply)
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *x11Server) setup(conn net.Conn) error {
+	var msg [12]byte
+	_, err := io.ReadFull(conn, msg[:])
+	if err != nil {
+		return err
+	}
+	if msg[0] != 'l' || binary.LittleEndian.Uint16(msg[2:]) != 11 {
+		return fmt.Errorf("unexpected setup %v", msg)
+	}
+	nameLen, dataLen := int(binary.LittleEndian.Uint16(msg[6:])), int(binary.LittleEndian.Uint16(msg[8:]))
+	auth := make([]byte, nameLen+pad4(nameLen)+dataLen+pad4(dataLen))
+	_, err = io.ReadFull(conn, auth)
+	if err != nil {
+		return err
+	}
+	name, data := string(auth[:nameLen]), auth[nameLen+pad4(nameLen):][:dataLen]
+
+	if s.auth != nil && (name != "MIT-MAGIC-COOKIE-1" || !bytes.Equal(data, s.auth)) {
+		reason := "Authorization required, but no authorization protocol specified\n"
+		reply := make([]byte, 8, 8+len(reason)+pad4(len(reason)))
+		reply[1] = byte(len(reason))
+		binary.LittleEndian.PutUint16(reply[6:], uint16(cap(reply)/4-2))
+		reply = append(reply, reason...)
+		reply = append(reply, make([]byte, pad4(len(reason)))...)
+		_, err = conn.Write(reply)
+		return err
+	}
+
+	const vendor = "sendkeys"
+	reply := make([]byte, 40, 40+len(vendor)+pad4(len(vendor)))
+	reply[0] = 1
+	binary.LittleEndian.PutUint16(reply[2:], 11)
+	binary.LittleEndian.PutUint16(reply[6:], uint16(cap(reply)/4-2))
+	binary.LittleEndian.PutUint16(reply[24:], uint16(len(vendor)))
+	reply[34], reply[35] = 8, 255 // key codes
+	reply = append(reply, vendor...)
+	reply = append(reply, make([]byte, pad4(len(vendor)))...)
+	_, err = conn.Write(reply)
+	return err
+}
+
+// newX11Server starts an X server that accepts a single connection on a unix socket.
+func newX11Server(t *testing.T) (*x11Server, string) {
+	t.Helper()
+	// a socket path as display, like the one of XQuartz
+	path := filepath.Join(t.TempDir(), "x11:0")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	s := &x11Server{done: make(chan struct{})}
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			close(s.done)
+			return
+		}
+		s.serve(conn)
+	}()
+	return s, path
+}
+
+func newX11KBWrap(t *testing.T) (*KBWrap, *x11Server) {
+	t.Helper()
+	server, display := newX11Server(t)
+	b, err := DialX11(context.Background(), X11Config{Display: display})
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := NewKBWrapWithOptions(
+		WithBackend(b),
+		WithKeyMap(b.KeyMap()),
+		WithKeysymMap(nil),
+		KeystrokeDuration(0),
+		DelayAfter(0),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k, server
+}
+
+func TestX11Backend(t *testing.T) {
+	k, server := newX11KBWrap(t)
+
+	err := errors.Join(
+		k.Type("A@\n"),
+		k.Press(KeyF5, ModCtrl),
+		k.Chord("shift+tab"),
+		k.Send("{Ctrl down}{AltGr down}{AltGr up}{Ctrl up}"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = k.Type("→")
+	if !errors.Is(err, ErrNoKeycode) {
+		t.Errorf("expected ErrNoKeycode, got %v", err)
+	}
+	err = k.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []x11Event{
+		{true, 50}, {true, 38}, {false, 38}, {false, 50}, // A
+		{true, 92}, {true, 24}, {false, 24}, {false, 92}, // @
+		{true, 36}, {false, 36}, // Return
+		{true, 37}, {true, 71}, {false, 71}, {false, 37}, // ctrl+F5
+		{true, 50}, {true, 23}, {false, 23}, {false, 50}, // shift+Tab
+		{true, 37}, {true, 92}, {false, 92}, {false, 37}, // held modifiers
+	}
+	events := server.Events()
+	if server.err != nil {
+		t.Fatal(server.err)
+	}
+	if fmt.Sprint(events) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, events)
+	}
+}
+
+func TestX11BackendKeyMap(t *testing.T) {
+	_, display := newX11Server(t)
+	b, err := DialX11(context.Background(), X11Config{Display: display})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	keyMap := b.KeyMap()
+	for r, expected := range map[rune]KeyCode{
+		'a':  SimpleKeyCode(38),
+		'A':  ShiftKeyCode(38),
+		'æ':  AltGrKeyCode(38),
+		'Æ':  AltGrShiftKeyCode(38),
+		'!':  ShiftKeyCode(10),
+		'@':  AltGrKeyCode(24),
+		'€':  AltGrKeyCode(26),
+		'¥':  AltGrShiftKeyCode(29),
+		'y':  SimpleKeyCode(52),
+		'Y':  ShiftKeyCode(52),
+		'ä':  SimpleKeyCode(202),
+		'ẞ':  AltGrKeyCode(202),
+		'ß':  AltGrShiftKeyCode(202),
+		' ':  SimpleKeyCode(65),
+		'\n': SimpleKeyCode(36),
+	} {
+		if keyMap[r] != expected {
+			t.Errorf("%q: expected %v, got %v", r, expected, keyMap[r])
+		}
+	}
+	if len(keyMap) != 24 {
+		t.Errorf("expected 24 characters, got %d: %v", len(keyMap), keyMap)
+	}
+}
+
+func TestX11BackendError(t *testing.T) {
+	server, display := newX11Server(t)
+	server.fail = 24
+	b, err := DialX11(context.Background(), X11Config{Display: display})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	err = b.Press(AltGrKeyCode(24))
+	if !errors.Is(err, ErrX11) {
+		t.Errorf("expected ErrX11, got %v", err)
+	}
+	err = errors.Join(b.Release(SimpleKeyCode(24)), b.Press(SimpleKeyCode(38)), b.Release(SimpleKeyCode(38)))
+	if !errors.Is(err, ErrX11) {
+		t.Errorf("expected ErrX11 of the release, got %v", err)
+	}
+	err = errors.Join(b.Press(SimpleKeyCode(38)), b.Release(SimpleKeyCode(38)))
+	if err != nil {
+		t.Errorf("expected the connection to be usable after an error, got %v", err)
+	}
+	for _, key := range []KeyCode{SimpleKeyCode(3), SimpleKeyCode(256), KeysymKeyCode(0x8fd)} {
+		err = b.Press(key)
+		if !errors.Is(err, ErrNoKeycode) {
+			t.Errorf("%v: expected ErrNoKeycode, got %v", key, err)
+		}
+	}
+	b.Close()
+
+	// the modifier that was pressed before the rejected key is released by Press
+	expected := []x11Event{{true, 92}, {false, 92}, {true, 38}, {false, 38}, {true, 38}, {false, 38}}
+	if events := server.Events(); fmt.Sprint(events) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, events)
+	}
+}
+
+func TestX11BackendHandshake(t *testing.T) {
+	cookie := []byte("0123456789abcdef")
+	xauth := filepath.Join(t.TempDir(), "Xauthority")
+	var file []byte
+	for _, entry := range [][5]string{
+		{"\x01\x00", "other", "0", "MIT-MAGIC-COOKIE-1", "wrong"},
+		{"\xff\xff", "", "1", "MIT-MAGIC-COOKIE-1", "wrong"},
+		{"\xff\xff", "", "0", "MIT-MAGIC-COOKIE-1", string(cookie)},
+	} {
+		file = append(file, entry[0]...)
+		for _, field := range entry[1:] {
+			file = binary.BigEndian.AppendUint16(file, uint16(len(field)))
+			file = append(file, field...)
+		}
+	}
+	err := os.WriteFile(xauth, file, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XAUTHORITY", xauth)
+
+	t.Run("xauthority", func(t *testing.T) {
+		server, display := newX11Server(t)
+		server.auth = cookie
+		t.Setenv("DISPLAY", display)
+		b, err := DialX11(context.Background(), X11Config{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.Close()
+	})
+	t.Run("refused", func(t *testing.T) {
+		server, display := newX11Server(t)
+		server.auth = cookie
+		_, err := DialX11(context.Background(), X11Config{Display: display, AuthName: "MIT-MAGIC-COOKIE-1", AuthData: []byte("wrong")})
+		if !errors.Is(err, ErrX11Refused) {
+			t.Errorf("expected ErrX11Refused, got %v", err)
+		}
+	})
+	t.Run("no XTEST", func(t *testing.T) {
+		server, display := newX11Server(t)
+		server.noXTEST = true
+		_, err := DialX11(context.Background(), X11Config{Display: display})
+		if !errors.Is(err, ErrNoXTEST) {
+			t.Errorf("expected ErrNoXTEST, got %v", err)
+		}
+	})
+}
+
+func TestParseX11Display(t *testing.T) {
+	for display, expected := range map[string][3]string{
+		":0":                 {"unix", "/tmp/.X11-unix/X0", "0"},
+		"unix:1.0":           {"unix", "/tmp/.X11-unix/X1", "1"},
+		"localhost:10.0":     {"tcp", "localhost:6010", "10"},
+		"[::1]:2":            {"tcp", "[::1]:6002", "2"},
+		"/tmp/org.xquartz:0": {"unix", "/tmp/org.xquartz:0", "0"},
+	} {
+		network, address, number, err := parseX11Display(display)
+		if err != nil {
+			t.Errorf("%s: %v", display, err)
+			continue
+		}
+		if got := [3]string{network, address, number}; got != expected {
+			t.Errorf("%s: expected %v, got %v", display, expected, got)
+		}
+	}
+	for _, display := range []string{"", "localhost", ":x", ":-1"} {
+		_, _, _, err := parseX11Display(display)
+		if !errors.Is(err, ErrX11) {
+			t.Errorf("%q: expected ErrX11, got %v", display, err)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package sendkeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrInvalidChord is returned when a chord cannot be parsed.
+var ErrInvalidChord = errors.New("invalid chord")
+
+// Chord is a keyboard shortcut of one or more keys that are pressed together.
+// The first key carries the modifiers, which are held until all keys are released.
+type Chord []KeyCode
+
+// String returns the human readable form of the chord, e.g. "ctrl+alt+t".
+func (c Chord) String() string {
+	parts := make([]string, 0, len(c))
+	for _, key := range c {
+		parts = append(parts, key.String())
+	}
+	return strings.Join(parts, "+")
+}
+
+// Events returns the key events that press all keys of the chord in order
+// and release them in reverse order.
+func (c Chord) Events() []KeyEvent {
+	events := make([]KeyEvent, 0, 2*len(c))
+	for _, key := range c {
+		events = append(events, KeyDownEvent(key))
+	}
+	for i := len(c) - 1; i >= 0; i-- {
+		events = append(events, KeyUpEvent(c[i]))
+	}
+	return events
+}
+
+// chordModifiers maps modifier names to a modifier and its side.
+var chordModifiers = map[string]struct {
+	mod  Modifier
+	side Side
+}{
+	"shift":   {ModShift, SideAny},
+	"lshift":  {ModShift, SideLeft},
+	"rshift":  {ModShift, SideRight},
+	"ctrl":    {ModCtrl, SideAny},
+	"control": {ModCtrl, SideAny},
+	"lctrl":   {ModCtrl, SideLeft},
+	"rctrl":   {ModCtrl, SideRight},
+	"alt":     {ModAlt, SideAny},
+	"option":  {ModAlt, SideAny},
+	"opt":     {ModAlt, SideAny},
+	"lalt":    {ModAlt, SideLeft},
+	"ralt":    {ModAlt, SideRight},
+	"altgr":   {ModAltGr, SideAny},
+	"super":   {ModSuper, SideAny},
+	"cmd":     {ModSuper, SideAny},
+	"command": {ModSuper, SideAny},
+	"win":     {ModSuper, SideAny},
+	"meta":    {ModSuper, SideAny},
+	"lsuper":  {ModSuper, SideLeft},
+	"rsuper":  {ModSuper, SideRight},
+	"lcmd":    {ModSuper, SideLeft},
+	"rcmd":    {ModSuper, SideRight},
+	"lwin":    {ModSuper, SideLeft},
+	"rwin":    {ModSuper, SideRight},
+}
+
+// ParseChord parses a chord like "ctrl+shift+esc" or "cmd+shift+4" using the
+// default KeyMap of the current platform.
+func ParseChord(s string) (Chord, error) {
+	return ParseChordWithKeyMap(s, defaultKeyMap())
+}
+
+// ParseChordWithKeyMap parses a chord of modifiers and keys separated by "+".
+// Keys are either named keys (see ParseKey), raw key codes in hex like "0x1e",
+// raw keysyms like "keysym:0x20ac", or single characters that are looked up in the KeyMap. Letters are case insensitive,
+// "plus" or a trailing "++" select the plus character.
+// Modifiers can be prefixed with "l" or "r" in order to select the left or right key,
+// but all modifiers of a chord must be on the same side.
+func ParseChordWithKeyMap(s string, keyMap KeyMap) (Chord, error) {
+	return parseChord(s, keyMap, func(k Key) (KeyCode, error) {
+		return k.KeyCode()
+	})
+}
+
+// parseChord parses a chord and resolves named keys with namedKey.
+func parseChord(s string, keyMap KeyMap, namedKey func(Key) (KeyCode, error)) (Chord, error) {
+	tokens, err := chordTokens(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		mods  KeyCode
+		chord Chord
+	)
+	for _, token := range tokens {
+		if m, ok := chordModifiers[strings.ToLower(token)]; ok {
+			if m.side != SideAny {
+				if mods.ModifierSide != SideAny && mods.ModifierSide != m.side {
+					return nil, fmt.Errorf("%w: %q: mixed left and right modifiers", ErrInvalidChord, s)
+				}
+				mods.ModifierSide = m.side
+			}
+			m.mod.apply(&mods)
+			continue
+		}
+
+		key, err := chordKey(token, keyMap, namedKey)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %w", ErrInvalidChord, s, err)
+		}
+		chord = append(chord, key)
+	}
+	if len(chord) == 0 {
+		return nil, fmt.Errorf("%w: %q: no key", ErrInvalidChord, s)
+	}
+
+	chord[0] = withModifiers(chord[0], mods)
+	for _, key := range chord[1:] {
+		if key.hasModifiers() || key.ModifierSide != SideAny {
+			return nil, fmt.Errorf("%w: %q: only the first key may need modifiers", ErrInvalidChord, s)
+		}
+	}
+	return chord, nil
+}
+
+// parseChordModifiers parses a chord that consists of modifiers only, e.g. "ctrl+shift".
+// The modifiers are returned as flags of a KeyCode without code.
+func parseChordModifiers(s string) (mods KeyCode, ok bool) {
+	tokens, err := chordTokens(s)
+	if err != nil || len(tokens) == 0 {
+		return KeyCode{}, false
+	}
+	for _, token := range tokens {
+		m, ok := chordModifiers[strings.ToLower(token)]
+		if !ok {
+			return KeyCode{}, false
+		}
+		if m.side != SideAny {
+			mods.ModifierSide = m.side
+		}
+		m.mod.apply(&mods)
+	}
+	return mods, true
+}
+
+// withModifiers adds the modifiers of mods to the key.
+func withModifiers(key, mods KeyCode) KeyCode {
+	key.ModifierSuper = key.ModifierSuper || mods.ModifierSuper
+	key.ModifierALT = key.ModifierALT || mods.ModifierALT
+	key.ModifierCTRL = key.ModifierCTRL || mods.ModifierCTRL
+	key.ModifierSHIFT = key.ModifierSHIFT || mods.ModifierSHIFT
+	key.ModifierALTGR = key.ModifierALTGR || mods.ModifierALTGR
+	if mods.ModifierSide != SideAny {
+		key.ModifierSide = mods.ModifierSide
+	}
+	return key
+}
+
+// chordTokens splits the chord at "+" signs.
+func chordTokens(s string) ([]string, error) {
+	parts := strings.Split(strings.TrimSpace(s), "+")
+	tokens := make([]string, 0, len(parts))
+	for i := 0; i < len(parts); i++ {
+		token := strings.TrimSpace(parts[i])
+		switch {
+		case token != "":
+			tokens = append(tokens, token)
+		case i == len(parts)-2 && parts[i+1] == "":
+			// trailing "++"
+			tokens = append(tokens, "+")
+			i++
+		default:
+			return nil, fmt.Errorf("%w: %q: empty key", ErrInvalidChord, s)
+		}
+	}
+	return tokens, nil
+}
+
+// chordKey resolves a single non-modifier key of a chord.
+func chordKey(token string, keyMap KeyMap, namedKey func(Key) (KeyCode, error)) (KeyCode, error) {
+	if r, size := utf8.DecodeRuneInString(token); size == len(token) {
+		code, ok := keyMap[unicode.ToLower(r)]
+		if !ok {
+			return KeyCode{}, fmt.Errorf("%w: %q", ErrKeyMappingNotFound, r)
+		}
+		return code, nil
+	}
+	if strings.EqualFold(token, "plus") {
+		return chordKey("+", keyMap, namedKey)
+	}
+	if hex, ok := strings.CutPrefix(token, "keysym:0x"); ok {
+		ks, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return KeyCode{}, err
+		}
+		return KeysymKeyCode(uint32(ks)), nil
+	}
+	if strings.HasPrefix(token, "0x") {
+		code, err := strconv.ParseInt(token[2:], 16, 32)
+		if err != nil {
+			return KeyCode{}, err
+		}
+		return SimpleKeyCode(int(code)), nil
+	}
+	key, err := ParseKey(token)
+	if err != nil {
+		return KeyCode{}, err
+	}
+	return namedKey(key)
+}
+
+// Chord presses the given chords one after another, e.g. "ctrl+a", "ctrl+c".
+// All chords are parsed with the KeyMap of the wrapper before any key is pressed.
+func (kb *KBWrap) Chord(chords ...string) error {
+	return kb.ChordContext(context.Background(), chords...)
+}
+
+// ChordContext presses the given chords one after another unless the context is cancelled.
+func (kb *KBWrap) ChordContext(ctx context.Context, chords ...string) error {
+	parsed := make([]Chord, 0, len(chords))
+	for _, s := range chords {
+		c, err := parseChord(s, kb.keyMap, kb.namedKey)
+		if err != nil {
+			return err
+		}
+		parsed = append(parsed, c)
+	}
+
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+
+	for _, c := range parsed {
+		_, err := kb.send(ctx, c.Events())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package sendkeys
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseChord(t *testing.T) {
+	keyMap := KeyMapLinuxQuartz()
+	esc := platformKeys[KeyEscape]
+
+	tests := map[string]Chord{
+		"ctrl+shift+esc": {{Code: esc, ModifierCTRL: true, ModifierSHIFT: true}},
+		"Ctrl+Alt+T":     {{Code: keyMap['t'].Code, ModifierCTRL: true, ModifierALT: true}},
+		"cmd+shift+4":    {{Code: keyMap['4'].Code, ModifierSuper: true, ModifierSHIFT: true}},
+		"rctrl+rshift+a": {{Code: keyMap['a'].Code, ModifierCTRL: true, ModifierSHIFT: true, ModifierSide: SideRight}},
+		"ctrl++":         {{Code: keyMap['+'].Code, ModifierCTRL: true, ModifierSHIFT: true}},
+		"ctrl+plus":      {{Code: keyMap['+'].Code, ModifierCTRL: true, ModifierSHIFT: true}},
+		"alt+0x1e":       {{Code: 0x1e, ModifierALT: true}},
+		"ctrl+a+b":       {{Code: keyMap['a'].Code, ModifierCTRL: true}, keyMap['b']},
+	}
+	for s, expected := range tests {
+		t.Run(s, func(t *testing.T) {
+			c, err := ParseChordWithKeyMap(s, keyMap)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(c, expected) {
+				t.Fatalf("expected %v, got %v", expected, c)
+			}
+
+			// the human readable form can be parsed again
+			again, err := ParseChordWithKeyMap(c.String(), keyMap)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(again, c) {
+				t.Fatalf("expected %v, got %v", c, again)
+			}
+		})
+	}
+
+	for _, s := range []string{"", "ctrl", "ctrl+", "lctrl+rshift+a", "ctrl+nope", "a+!"} {
+		_, err := ParseChordWithKeyMap(s, keyMap)
+		if !errors.Is(err, ErrInvalidChord) {
+			t.Errorf("%q: expected %v, got %v", s, ErrInvalidChord, err)
+		}
+	}
+}
+
+func TestKeyCodeString(t *testing.T) {
+	keyMap := KeyMapLinuxQuartz()
+	tests := map[string]KeyCode{
+		"a":             SimpleKeyCode(0x1e),
+		"0x1ff":         SimpleKeyCode(0x1ff),
+		"lctrl+lalt+a":  {Code: 0x1e, ModifierCTRL: true, ModifierALT: true, ModifierSide: SideLeft},
+		"ctrl+alt+t":    {Code: keyMap['t'].Code, ModifierCTRL: true, ModifierALT: true},
+		"super+shift+4": {Code: keyMap['4'].Code, ModifierSuper: true, ModifierSHIFT: true},
+		"ctrl+shift+=":  {Code: keyMap['+'].Code, ModifierCTRL: true, ModifierSHIFT: true},
+		"ctrl+c":        {Keysym: 'c', ModifierCTRL: true},
+		"f5":            KeysymKeyCode(0xffc2),
+		"space":         KeysymKeyCode(' '),
+		"plus":          KeysymKeyCode('+'),
+		"keysym:0x41":   KeysymKeyCode('A'),
+		"keysym:0xfe50": KeysymKeyCode(0xfe50),
+		"super+altgr+shift+f5": {
+			Code: platformKeys[KeyF5], ModifierSuper: true, ModifierALTGR: true, ModifierSHIFT: true,
+		},
+		"rctrl+rshift": {ModifierCTRL: true, ModifierSHIFT: true, ModifierSide: SideRight},
+	}
+	for expected, k := range tests {
+		if k.String() != expected {
+			t.Errorf("expected %q, got %q", expected, k.String())
+		}
+	}
+}
+
+func TestChordStringRoundTrip(t *testing.T) {
+	tests := map[string]string{
+		"Ctrl+Alt+T":    "ctrl+alt+t",
+		"cmd+shift+4":   "super+shift+4",
+		"lalt+tab":      "lalt+tab",
+		"ctrl+a+b":      "ctrl+a+b",
+		"0x1ff":         "0x1ff",
+		"keysym:0xfe50": "keysym:0xfe50",
+	}
+	for s, expected := range tests {
+		c, err := ParseChord(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.String() != expected {
+			t.Errorf("%q: expected %q, got %q", s, expected, c.String())
+		}
+		again, err := ParseChord(c.String())
+		if err != nil {
+			t.Fatalf("%q: %v", c.String(), err)
+		}
+		if !reflect.DeepEqual(again, c) {
+			t.Errorf("%q: expected %v, got %v", c.String(), c, again)
+		}
+	}
+
+	// keysym mode
+	k, _ := newRecordingKBWrap(t, WithKeysymMap(nil))
+	for s, expected := range map[string]string{
+		"Ctrl+C":     "ctrl+c",
+		"alt+F5":     "alt+f5",
+		"shift+plus": "shift+plus",
+		"ctrl+a+b":   "ctrl+a+b",
+		"super+é":    "super+é",
+	} {
+		c, err := parseChord(s, k.keyMap, k.namedKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.String() != expected {
+			t.Errorf("%q: expected %q, got %q", s, expected, c.String())
+		}
+		again, err := parseChord(c.String(), k.keyMap, k.namedKey)
+		if err != nil {
+			t.Fatalf("%q: %v", c.String(), err)
+		}
+		if !reflect.DeepEqual(again, c) {
+			t.Errorf("%q: expected %v, got %v", c.String(), c, again)
+		}
+	}
+}
+
+func TestKeyCodeSideJSON(t *testing.T) {
+	k := KeyCode{Code: 1, ModifierCTRL: true, ModifierSide: SideRight}
+	data, err := json.Marshal(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded KeyCode
+	err = json.Unmarshal(data, &loaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded != k {
+		t.Fatalf("expected %v, got %v: %s", k, loaded, data)
+	}
+}
+
+func TestChord(t *testing.T) {
+	k, rec := newRecordingKBWrap(t, WithKeyMap(KeyMapLinuxQuartz()))
+
+	err := k.Chord("ctrl+a", "ctrl+c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.Events()) != 4 {
+		t.Fatalf("expected 4 events, got %v", rec.Events())
+	}
+
+	err = k.Chord("ctrl+c", "ctrl+nope")
+	if !errors.Is(err, ErrInvalidChord) {
+		t.Fatalf("expected %v, got %v", ErrInvalidChord, err)
+	}
+	if len(rec.Events()) != 4 {
+		t.Fatalf("expected no further events, got %v", rec.Events())
+	}
+}
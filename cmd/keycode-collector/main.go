@@ -1,10 +1,11 @@
 package main
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"os"
+	"runtime"
 	"strconv"
 	"strings"
 	"sync"
@@ -16,26 +17,36 @@ import (
 
 func main() {
 	resultMap := map[string]sendkeys.KeyCode{}
+	sequences := map[string][]sendkeys.KeyCode{}
 	defer func() {
 		if i := recover(); i != nil {
 			fmt.Println(i)
 		}
 
-		data, _ := json.MarshalIndent(resultMap, "", " ")
-		fmt.Println(string(data))
+		f := &sendkeys.KeyMapFile{
+			Version:   sendkeys.KeyMapFileVersion,
+			Platform:  runtime.GOOS,
+			Keys:      resultMap,
+			Sequences: sequences,
+		}
+		_ = f.Encode(os.Stdout)
 	}()
 
 	for promptContinue("Continue filling the key codes map?") {
-		cfg, err := configure()
-		if err != nil {
-			log.Println(err)
-			return
-		}
+		var codes []sendkeys.KeyCode
+		for len(codes) == 0 || promptConfirm("Does the character need another key (e.g. after a dead key)?") {
+			cfg, err := configure()
+			if err != nil {
+				log.Println(err)
+				return
+			}
 
-		code, err := selectMultiStep(cfg)
-		if err != nil {
-			log.Println(err)
-			return
+			code, err := selectMultiStep(cfg)
+			if err != nil {
+				log.Println(err)
+				return
+			}
+			codes = append(codes, code)
 		}
 
 		r, err := promptRune()
@@ -43,7 +54,11 @@ func main() {
 			log.Println(err)
 			return
 		}
-		resultMap[string(r)] = code
+		if len(codes) == 1 {
+			resultMap[string(r)] = codes[0]
+		} else {
+			sequences[string(r)] = codes
+		}
 	}
 }
 
@@ -301,6 +316,17 @@ func promptRune() (r rune, err error) {
 	return []rune(result)[0], nil
 }
 
+// promptConfirm asks a yes/no question that defaults to no.
+func promptConfirm(question string) bool {
+	prompt := promptui.Prompt{
+		Default:   "N",
+		IsConfirm: true,
+		Label:     question,
+	}
+	_, err := prompt.Run()
+	return err == nil
+}
+
 func promptContinue(question string) bool {
 	prompt := promptui.Prompt{
 		Default:   "Y",
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jxsl13/sendkeys"
+)
+
+const usage = `Usage: sendkeys [flags] [text...]
+
+Types the given text, the content of a file or the standard input
+by simulating key events. Every line is followed by the line ending.
+
+Flags:
+`
+
+// keyMaps are the built-in key maps that can be selected with -keymap.
+var keyMaps = map[string]func() (sendkeys.KeyMap, sendkeys.KeySequences){
+	"us-en101": func() (sendkeys.KeyMap, sendkeys.KeySequences) {
+		return sendkeys.KeyMap_US_EN101(), nil
+	},
+	"linux-quartz": func() (sendkeys.KeyMap, sendkeys.KeySequences) {
+		return sendkeys.KeyMapLinuxQuartz(), nil
+	},
+	"darwin-de-qwertz": func() (sendkeys.KeyMap, sendkeys.KeySequences) {
+		return sendkeys.KeyMapDarwin_DE_QWERTZ(), sendkeys.KeySequencesDarwin_DE_QWERTZ()
+	},
+}
+
+type config struct {
+	keyMap     string
+	keyMapFile string
+	file       string
+	countdown  time.Duration
+	before     time.Duration
+	hold       time.Duration
+	after      time.Duration
+	random     bool
+	lineEnding string
+	dryRun     bool
+	format     string
+	unmapped   string
+	substitute string
+	fallback   string
+	args       []string
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sendkeys:", err)
+		os.Exit(1)
+	}
+}
+
+func parseFlags(args []string, stderr io.Writer) (config, error) {
+	var cfg config
+	fs := flag.NewFlagSet("sendkeys", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+
+	fs.StringVar(&cfg.keyMap, "keymap", "", "built-in key map (us-en101, linux-quartz, darwin-de-qwertz) or xkb:<layout>, defaults to the platform key map")
+	fs.StringVar(&cfg.keyMapFile, "keymap-file", "", "JSON or YAML key map file")
+	fs.StringVar(&cfg.file, "file", "", "read the text from a file, - for standard input")
+	fs.DurationVar(&cfg.countdown, "countdown", 5*time.Second, "wait before typing, e.g. in order to focus the target window")
+	fs.DurationVar(&cfg.before, "before", 0, "delay before every key press")
+	fs.DurationVar(&cfg.hold, "hold", 40*time.Millisecond, "duration every key is held down")
+	fs.DurationVar(&cfg.after, "after", 10*time.Millisecond, "delay after every key press")
+	fs.BoolVar(&cfg.random, "random", false, "vary the delays by up to 50%")
+	fs.StringVar(&cfg.lineEnding, "line-ending", "enter", "key pressed after every line: enter, shift-enter or none")
+	fs.BoolVar(&cfg.dryRun, "dry-run", false, "print the planned key events instead of typing them")
+	fs.StringVar(&cfg.format, "format", "table", "output format of the dry run: table or json")
+	fs.StringVar(&cfg.unmapped, "unmapped", "abort", "handling of characters that cannot be typed: abort, skip, substitute or fallback")
+	fs.StringVar(&cfg.substitute, "substitute", "?", "character typed instead of characters that cannot be typed, implies -unmapped substitute")
+	fs.StringVar(&cfg.fallback, "fallback", "unicode-hex", "input method of -unmapped fallback: unicode-hex (Ctrl+Shift+U) or alt-numpad")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.args = fs.Args()
+
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "substitute" {
+			cfg.unmapped = "substitute"
+		}
+	})
+	if utf8.RuneCountInString(cfg.substitute) != 1 {
+		return cfg, fmt.Errorf("invalid substitute: %q", cfg.substitute)
+	}
+	switch cfg.unmapped {
+	case "abort", "skip", "substitute", "fallback":
+	default:
+		return cfg, fmt.Errorf("invalid unmapped policy: %q", cfg.unmapped)
+	}
+	switch cfg.fallback {
+	case "unicode-hex", "alt-numpad":
+	default:
+		return cfg, fmt.Errorf("invalid fallback: %q", cfg.fallback)
+	}
+
+	switch cfg.format {
+	case "table", "json":
+	default:
+		return cfg, fmt.Errorf("invalid format: %q", cfg.format)
+	}
+	switch cfg.lineEnding {
+	case "enter", "shift-enter", "none":
+	default:
+		return cfg, fmt.Errorf("invalid line ending: %q", cfg.lineEnding)
+	}
+	if cfg.keyMap != "" && cfg.keyMapFile != "" {
+		return cfg, errors.New("-keymap and -keymap-file are mutually exclusive")
+	}
+	if cfg.file != "" && len(cfg.args) > 0 {
+		return cfg, errors.New("-file and text arguments are mutually exclusive")
+	}
+	return cfg, nil
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	cfg, err := parseFlags(args, stderr)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	lines, err := readLines(cfg, stdin)
+	if err != nil {
+		return err
+	}
+
+	opts, err := options(cfg)
+	if err != nil {
+		return err
+	}
+
+	if cfg.dryRun {
+		format := sendkeys.DryRunTable
+		if cfg.format == "json" {
+			format = sendkeys.DryRunJSON
+		}
+		opts = append(opts, sendkeys.DryRun(stdout, format))
+	}
+
+	if !cfg.dryRun {
+		// validate without creating the keyboard device
+		v, err := sendkeys.NewKBWrapWithOptions(append(opts, sendkeys.DryRun(io.Discard, sendkeys.DryRunTable))...)
+		if err != nil {
+			return err
+		}
+		err = validate(v, cfg.unmapped, lines, stderr)
+		if err != nil {
+			return err
+		}
+	}
+
+	kb, err := sendkeys.NewKBWrapWithOptions(opts...)
+	if err != nil {
+		return err
+	}
+	defer kb.Close()
+
+	if !cfg.dryRun {
+		err = countdown(ctx, cfg.countdown, stderr)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cfg.dryRun {
+		// list the characters of all lines that cannot be typed instead of any key event,
+		// like the validation aborts before the keyboard device is created
+		err = kb.PlanUnmapped(lines)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, line := range lines {
+		_, err = kb.TypeContext(ctx, line)
+		if err != nil {
+			return err
+		}
+		err = lineEnding(ctx, kb, cfg.lineEnding)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func options(cfg config) ([]sendkeys.KBOpt, error) {
+	opts := []sendkeys.KBOpt{
+		sendkeys.DelayBefore(cfg.before),
+		sendkeys.KeystrokeDuration(cfg.hold),
+		sendkeys.DelayAfter(cfg.after),
+	}
+	if cfg.random {
+		opts = append(opts, sendkeys.Random)
+	}
+	if cfg.countdown > 0 {
+		// the countdown replaces the initial delay of the uinput device
+		opts = append(opts, sendkeys.NoDelay)
+	}
+
+	switch cfg.unmapped {
+	case "skip":
+		opts = append(opts, sendkeys.WithUnmappedPolicy(sendkeys.UnmappedSkip))
+	case "substitute":
+		r, _ := utf8.DecodeRuneInString(cfg.substitute)
+		opts = append(opts, sendkeys.WithSubstitute(r))
+	case "fallback":
+		if cfg.fallback == "alt-numpad" {
+			opts = append(opts, sendkeys.WithFallback(sendkeys.AltNumpadInput()))
+		} else {
+			opts = append(opts, sendkeys.WithFallback(sendkeys.UnicodeHexInput()))
+		}
+	}
+
+	switch {
+	case cfg.keyMapFile != "":
+		opts = append(opts, sendkeys.WithKeyMapFile(cfg.keyMapFile))
+	case strings.HasPrefix(cfg.keyMap, "xkb:"):
+		keyMap, err := sendkeys.LoadXKBLayout(strings.TrimPrefix(cfg.keyMap, "xkb:"))
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, sendkeys.WithKeyMap(keyMap))
+	case cfg.keyMap != "":
+		builtin, ok := keyMaps[cfg.keyMap]
+		if !ok {
+			return nil, fmt.Errorf("unknown key map: %q", cfg.keyMap)
+		}
+		keyMap, sequences := builtin()
+		opts = append(opts, sendkeys.WithKeyMap(keyMap), sendkeys.WithKeySequences(sequences))
+	}
+	return opts, nil
+}
+
+// validate reports all characters that cannot be typed before typing starts.
+// Characters that are skipped or substituted are not reported.
+func validate(kb *sendkeys.KBWrap, policy string, lines []string, stderr io.Writer) error {
+	switch policy {
+	case "skip", "substitute":
+		return nil
+	}
+
+	var errs []error
+	for i, line := range lines {
+		for _, u := range kb.Validate(line) {
+			u.Line = i + 1
+			errs = append(errs, &sendkeys.UnmappedError{UnmappedRune: u})
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	for _, err := range errs {
+		fmt.Fprintln(stderr, err)
+	}
+	return fmt.Errorf("%d characters cannot be typed: %w", len(errs), sendkeys.ErrKeyMappingNotFound)
+}
+
+// readLines reads the text from the arguments, a file or the standard input.
+func readLines(cfg config, stdin io.Reader) ([]string, error) {
+	if len(cfg.args) > 0 {
+		return strings.Split(strings.Join(cfg.args, " "), "\n"), nil
+	}
+
+	r := stdin
+	if cfg.file != "" && cfg.file != "-" {
+		f, err := os.Open(cfg.file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimSuffix(scanner.Text(), "\r"))
+	}
+	return lines, scanner.Err()
+}
+
+func countdown(ctx context.Context, d time.Duration, stderr io.Writer) error {
+	if d <= 0 {
+		return nil
+	}
+	fmt.Fprintf(stderr, "typing in %s", d)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(stderr)
+			return ctx.Err()
+		case <-ticker.C:
+			fmt.Fprint(stderr, ".")
+		case <-timer.C:
+			fmt.Fprintln(stderr)
+			return nil
+		}
+	}
+}
+
+func lineEnding(ctx context.Context, kb *sendkeys.KBWrap, ending string) error {
+	switch ending {
+	case "enter":
+		return kb.PressContext(ctx, sendkeys.KeyEnter)
+	case "shift-enter":
+		return kb.PressContext(ctx, sendkeys.KeyEnter, sendkeys.ModShift)
+	default:
+		return nil
+	}
+}
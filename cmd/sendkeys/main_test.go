@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jxsl13/sendkeys"
+)
+
+// event is a planned key event of the JSON dry run output.
+type event struct {
+	Type string `json:"type"`
+	Key  *struct {
+		Code  int  `json:"code"`
+		Shift bool `json:"shift"`
+	} `json:"key"`
+	Char   string `json:"char"`
+	Offset int    `json:"offset"`
+	Line   int    `json:"line"`
+}
+
+// runDryRun runs the command with a JSON dry run of the linux-quartz key map.
+func runDryRun(t *testing.T, stdin string, args ...string) ([]event, string, error) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	args = append([]string{"-dry-run", "-format", "json", "-keymap", "linux-quartz"}, args...)
+	err := run(context.Background(), args, strings.NewReader(stdin), &stdout, &stderr)
+
+	var events []event
+	dec := json.NewDecoder(&stdout)
+	for dec.More() {
+		var e event
+		if err := dec.Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, e)
+	}
+	return events, stderr.String(), err
+}
+
+// typed returns the characters of the down events and a newline for every Enter.
+func typed(events []event) string {
+	var sb strings.Builder
+	for _, e := range events {
+		switch {
+		case e.Type != "down":
+		case e.Char != "":
+			sb.WriteString(e.Char)
+		case e.Key != nil && e.Key.Code == 28:
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String()
+}
+
+func TestFlags(t *testing.T) {
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"-keymap", "us-en101", "-keymap-file", "keys.json", "x"}, "mutually exclusive"},
+		{[]string{"-file", "text.txt", "x"}, "mutually exclusive"},
+		{[]string{"-unmapped", "ignore", "x"}, "invalid unmapped policy"},
+		{[]string{"-format", "xml", "x"}, "invalid format"},
+		{[]string{"-line-ending", "crlf", "x"}, "invalid line ending"},
+		{[]string{"-substitute", "??", "x"}, "invalid substitute"},
+		{[]string{"-fallback", "compose", "x"}, "invalid fallback"},
+		{[]string{"-keymap", "dvorak", "x"}, "unknown key map"},
+		{[]string{"-nope"}, "not defined"},
+	}
+	for _, tc := range tests {
+		var stdout, stderr bytes.Buffer
+		err := run(context.Background(), append([]string{"-dry-run"}, tc.args...), strings.NewReader(""), &stdout, &stderr)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%v: expected error %q, got %v", tc.args, tc.err, err)
+		}
+		if stdout.Len() != 0 {
+			t.Errorf("%v: unexpected output %q", tc.args, stdout.String())
+		}
+	}
+}
+
+func TestReadLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "text.txt")
+	err := os.WriteFile(path, []byte("ab\r\ncd\r\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		stdin string
+		args  []string
+	}{
+		{"stdin", "ab\r\ncd\r\n", nil},
+		{"stdin without final newline", "ab\ncd", nil},
+		{"file", "", []string{"-file", path}},
+		{"dash", "ab\r\ncd\r\n", []string{"-file", "-"}},
+		{"args", "", []string{"ab\ncd"}},
+	}
+	for _, tc := range tests {
+		events, _, err := runDryRun(t, tc.stdin, tc.args...)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if s := typed(events); s != "ab\ncd\n" {
+			t.Errorf("%s: expected %q, got %q", tc.name, "ab\ncd\n", s)
+		}
+	}
+}
+
+func TestLineEnding(t *testing.T) {
+	tests := []struct {
+		ending   string
+		expected string
+		shift    bool
+	}{
+		{"enter", "a\nb\n", false},
+		{"shift-enter", "a\nb\n", true},
+		{"none", "ab", false},
+	}
+	for _, tc := range tests {
+		events, _, err := runDryRun(t, "", "-line-ending", tc.ending, "a\nb")
+		if err != nil {
+			t.Errorf("%s: %v", tc.ending, err)
+			continue
+		}
+		if s := typed(events); s != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.ending, tc.expected, s)
+		}
+		for _, e := range events {
+			if e.Char == "" && e.Key != nil && e.Key.Shift != tc.shift {
+				t.Errorf("%s: unexpected modifiers of the line ending: %+v", tc.ending, e)
+			}
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	err := run(context.Background(), []string{"-keymap", "linux-quartz", "-countdown", "0", "aé\nbö"}, strings.NewReader(""), &stdout, &stderr)
+	if err == nil || !strings.Contains(err.Error(), "2 characters cannot be typed") {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "1:2: ") || !strings.HasPrefix(lines[1], "2:2: ") {
+		t.Errorf("unexpected output: %q", stderr.String())
+	}
+
+	// skipped and substituted characters are not reported
+	kb, err := sendkeys.NewKBWrapWithOptions(sendkeys.DryRun(io.Discard, sendkeys.DryRunTable), sendkeys.WithKeyMap(sendkeys.KeyMapLinuxQuartz()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, policy := range []string{"skip", "substitute"} {
+		stderr.Reset()
+		err = validate(kb, policy, []string{"aé"}, &stderr)
+		if err != nil || stderr.Len() != 0 {
+			t.Errorf("%s: unexpected error %v: %q", policy, err, stderr.String())
+		}
+	}
+}
+
+func TestDryRunUnmapped(t *testing.T) {
+	events, _, err := runDryRun(t, "", "aé\nb\nbö")
+	if err == nil || !strings.Contains(err.Error(), "1:2: ") || !strings.Contains(err.Error(), "3:2: ") {
+		t.Fatalf("expected errors of line 1 and 3, got %v", err)
+	}
+	// like the validation, the unmapped characters of all lines are listed instead of any key event
+	expected := []event{{Type: "unmapped", Char: "é", Offset: 1, Line: 1}, {Type: "unmapped", Char: "ö", Offset: 7, Line: 3}}
+	if !reflect.DeepEqual(events, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, events)
+	}
+}
@@ -0,0 +1,168 @@
+package sendkeys
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// DryRunFormat is the output format of the dry run.
+type DryRunFormat int
+
+const (
+	// DryRunTable renders one human readable table row per key event.
+	DryRunTable DryRunFormat = iota
+	// DryRunJSON renders one JSON object per line and key event.
+	DryRunJSON
+)
+
+// DryRun writes the planned key events to w instead of sending them.
+// Delays are not waited for, but added to the time of the planned events.
+// Characters that cannot be typed are listed instead of the events of a Type call,
+// see also KBWrap.PlanUnmapped.
+// In case no Backend is provided, no keyboard device is created.
+func DryRun(w io.Writer, format DryRunFormat) KBOpt {
+	return func(k *KBWrap) {
+		k.dryRun = &planWriter{w: w, format: format}
+	}
+}
+
+// plannedEvent is a single line of the JSON output.
+type plannedEvent struct {
+	Type   string   `json:"type"`
+	At     string   `json:"at,omitempty"`
+	Delay  string   `json:"delay,omitempty"`
+	Key    *KeyCode `json:"key,omitempty"`
+	Char   string   `json:"char,omitempty"`
+	Offset *int     `json:"offset,omitempty"`
+	Line   int      `json:"line,omitempty"`
+	Col    int      `json:"col,omitempty"`
+}
+
+type planWriter struct {
+	w      io.Writer
+	format DryRunFormat
+	header bool
+	last   time.Duration // time of the previous event
+}
+
+func (p *planWriter) write(e plannedEvent) error {
+	if p.format == DryRunJSON {
+		return json.NewEncoder(p.w).Encode(e)
+	}
+
+	if !p.header {
+		p.header = true
+		_, err := fmt.Fprintf(p.w, "%-10s %-10s %-8s %-24s %-6s %s\n", "AT", "DELAY", "EVENT", "KEY", "CHAR", "OFFSET")
+		if err != nil {
+			return err
+		}
+	}
+	key, offset := "", ""
+	if e.Key != nil {
+		key = e.Key.String()
+	}
+	if e.Offset != nil {
+		offset = strconv.Itoa(*e.Offset)
+	}
+	char := e.Char
+	if char != "" {
+		char = strconv.QuoteRune([]rune(char)[0])
+	}
+	_, err := fmt.Fprintf(p.w, "%-10s %-10s %-8s %-24s %-6s %s\n", e.At, e.Delay, e.Type, key, char, offset)
+	return err
+}
+
+// event writes a planned key event at the current virtual time.
+func (p *planWriter) event(kb *KBWrap, down bool, key KeyCode) error {
+	e := plannedEvent{
+		Type:  "up",
+		At:    kb.clock.String(),
+		Delay: (kb.clock - p.last).String(),
+		Key:   &key,
+	}
+	if down {
+		e.Type = "down"
+	}
+	if c := kb.char; c != nil {
+		e.Char = string(c.Rune)
+		e.Offset = &c.Offset
+		e.Line = c.Line
+		e.Col = c.Col
+	}
+	p.last = kb.clock
+	return p.write(e)
+}
+
+// unmapped writes a character that cannot be typed.
+func (p *planWriter) unmapped(u UnmappedRune) error {
+	return p.write(plannedEvent{Type: "unmapped", Char: string(u.Rune), Offset: &u.Offset, Line: u.Line, Col: u.Col})
+}
+
+// planContext plans typing the string in dry run mode.
+// Like in a real Type call, no key is planned in case characters cannot be typed
+// according to the UnmappedPolicy. They are listed and returned as error instead.
+func (kb *KBWrap) planContext(ctx context.Context, s string, mods KeyCode, progress ProgressFunc) (n int, err error) {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+
+	keys, chars, errs := kb.resolve(s)
+	if len(errs) > 0 {
+		return 0, errors.Join(append(errs, kb.planUnmapped(errs))...)
+	}
+	return kb.typeKeys(ctx, keys, chars, mods, progress)
+}
+
+// PlanUnmapped lists all characters of the lines that cannot be typed in dry run mode,
+// e.g. before the lines are typed one after another. The returned error joins an
+// *UnmappedError for every character, whose Line is the index of the line starting
+// at 1 and whose Offset is the byte offset in the lines joined by "\n".
+// PlanUnmapped does nothing unless DryRun is set.
+func (kb *KBWrap) PlanUnmapped(lines []string) error {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+
+	if kb.dryRun == nil {
+		return nil
+	}
+
+	var (
+		all    []error
+		offset int
+	)
+	for i, line := range lines {
+		_, _, errs := kb.resolve(line)
+		for _, err := range errs {
+			var uerr *UnmappedError
+			if errors.As(err, &uerr) {
+				uerr.Line = i + 1
+				uerr.Offset += offset
+			}
+		}
+		err := kb.planUnmapped(errs)
+		if err != nil {
+			return err
+		}
+		all = append(all, errs...)
+		offset += len(line) + 1
+	}
+	return errors.Join(all...)
+}
+
+// planUnmapped writes the characters of all *UnmappedError.
+func (kb *KBWrap) planUnmapped(errs []error) error {
+	for _, err := range errs {
+		var uerr *UnmappedError
+		if errors.As(err, &uerr) {
+			err = kb.dryRun.unmapped(uerr.UnmappedRune)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
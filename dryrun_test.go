@@ -0,0 +1,164 @@
+package sendkeys
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDryRunJSON(t *testing.T) {
+	keyMap := KeyMapLinuxQuartz()
+	var buf bytes.Buffer
+	k, rec := newRecordingKBWrap(t,
+		WithKeyMap(keyMap),
+		DryRun(&buf, DryRunJSON),
+		DelayBefore(5*time.Millisecond),
+		KeystrokeDuration(time.Hour),
+		DelayAfter(10*time.Millisecond),
+	)
+
+	start := time.Now()
+	// like a real Type call, no key is planned in case a character cannot be typed
+	n, err := k.TypeContext(context.Background(), "a€b")
+	if !errors.Is(err, ErrKeyMappingNotFound) || n != 0 {
+		t.Fatalf("expected %v, got %d, %v", ErrKeyMappingNotFound, n, err)
+	}
+	n, err = k.TypeContext(context.Background(), "ab")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 planned characters, got %d", n)
+	}
+	if time.Since(start) > time.Minute {
+		t.Fatal("dry run must not wait")
+	}
+	if len(rec.Events()) != 0 {
+		t.Fatalf("dry run must not send events, got %v", rec.Events())
+	}
+
+	var events []plannedEvent
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var e plannedEvent
+		err = json.Unmarshal(scanner.Bytes(), &e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, e)
+	}
+	if len(events) != 5 {
+		t.Fatalf("expected 5 events, got %d: %+v", len(events), events)
+	}
+	if events[0].Type != "unmapped" || events[0].Char != "€" || *events[0].Offset != 1 {
+		t.Fatalf("expected unmapped character first, got %+v", events[0])
+	}
+
+	last := events[4]
+	if last.Type != "up" || last.Char != "b" || *last.Offset != 1 || *last.Key != keyMap['b'] {
+		t.Fatalf("unexpected last event: %+v", last)
+	}
+	// 5ms + 1h + 10ms + 5ms + 1h
+	if last.At != "2h0m0.02s" || last.Delay != "1h0m0s" {
+		t.Fatalf("unexpected timing: %+v", last)
+	}
+}
+
+func TestDryRunTable(t *testing.T) {
+	var buf bytes.Buffer
+	k, _ := newRecordingKBWrap(t, DryRun(&buf, DryRunTable))
+
+	err := k.Press(KeyEnter, ModShift)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "AT") {
+		t.Fatalf("unexpected table:\n%s", buf.String())
+	}
+	if !strings.Contains(lines[1], "down") || !strings.Contains(lines[1], "shift+enter") {
+		t.Fatalf("unexpected row: %s", lines[1])
+	}
+}
+
+func TestPlanUnmapped(t *testing.T) {
+	var buf bytes.Buffer
+	k, _ := newRecordingKBWrap(t, WithKeyMap(KeyMapLinuxQuartz()), DryRun(&buf, DryRunJSON))
+
+	lines := []string{"a€", "b", "ö"}
+	err := k.PlanUnmapped(lines)
+	if !errors.Is(err, ErrKeyMappingNotFound) {
+		t.Fatalf("expected %v, got %v", ErrKeyMappingNotFound, err)
+	}
+	// the listing does not change later Type calls
+	_, err = k.TypeContext(context.Background(), lines[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = k.TypeContext(context.Background(), lines[2])
+	if !errors.Is(err, ErrKeyMappingNotFound) {
+		t.Fatalf("expected %v, got %v", ErrKeyMappingNotFound, err)
+	}
+
+	var events []plannedEvent
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var e plannedEvent
+		err = dec.Decode(&e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, e)
+	}
+	expected := []plannedEvent{
+		{Type: "unmapped", Char: "€", Line: 1, Col: 2, Offset: intPtr(1)},
+		{Type: "unmapped", Char: "ö", Line: 3, Col: 1, Offset: intPtr(7)},
+		{Type: "down", Char: "b"},
+		{Type: "up", Char: "b"},
+		{Type: "unmapped", Char: "ö", Line: 1, Col: 1, Offset: intPtr(0)},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %d: %+v", len(expected), len(events), events)
+	}
+	for i, e := range events {
+		x := expected[i]
+		if e.Type != x.Type || e.Char != x.Char {
+			t.Errorf("expected %+v, got %+v", x, e)
+		}
+		if x.Type == "unmapped" && (e.Line != x.Line || e.Col != x.Col || *e.Offset != *x.Offset) {
+			t.Errorf("expected %+v, got %+v", x, e)
+		}
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestDryRunSessionUnmapped(t *testing.T) {
+	var buf bytes.Buffer
+	k, _ := newRecordingKBWrap(t, WithKeyMap(KeyMapLinuxQuartz()), DryRun(&buf, DryRunJSON))
+
+	_, err := k.Start("a€").Wait()
+	if !errors.Is(err, ErrKeyMappingNotFound) {
+		t.Fatalf("expected %v, got %v", ErrKeyMappingNotFound, err)
+	}
+	var events []plannedEvent
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var e plannedEvent
+		err = dec.Decode(&e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, e)
+	}
+	if len(events) != 1 || events[0].Type != "unmapped" || events[0].Char != "€" {
+		t.Errorf("expected only the unmapped character, got %+v", events)
+	}
+}
@@ -1,10 +1,83 @@
 package sendkeys
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
 
 // ErrKeyMappingNotFound is an error returned when we don't know how to handle the given character.
 var ErrKeyMappingNotFound = errors.New("failed to map key: ")
 
+// KeyPhase is the part of a keystroke in which an error occurred.
+type KeyPhase int
+
+const (
+	// PhaseDown is the key down event of a keystroke.
+	PhaseDown KeyPhase = iota
+	// PhaseUp is the key up event of a keystroke.
+	PhaseUp
+)
+
+func (p KeyPhase) String() string {
+	if p == PhaseUp {
+		return "up"
+	}
+	return "down"
+}
+
+// KeystrokeError is returned when a key event could not be sent.
+type KeystrokeError struct {
+	Index   int  // index of the typed character in the text, counted in characters, -1 if no character is typed
+	Rune    rune // typed character, 0 if no character is typed
+	KeyCode KeyCode
+	Phase   KeyPhase
+	Err     error
+}
+
+func (e *KeystrokeError) Error() string {
+	if e.Index < 0 {
+		return fmt.Sprintf("key %s %s: %v", e.Phase, e.KeyCode, e.Err)
+	}
+	return fmt.Sprintf("key %s %s of %q at index %d: %v", e.Phase, e.KeyCode, e.Rune, e.Index, e.Err)
+}
+
+func (e *KeystrokeError) Unwrap() error {
+	return e.Err
+}
+
+// keystrokeError wraps the error of a key event with the character that is currently typed.
+func (kb *KBWrap) keystrokeError(key KeyCode, phase KeyPhase, err error) error {
+	if err == nil {
+		return nil
+	}
+	e := &KeystrokeError{Index: -1, KeyCode: key, Phase: phase, Err: err}
+	if kb.char != nil {
+		e.Index = kb.char.Index
+		e.Rune = kb.char.Rune
+	}
+	return e
+}
+
+// Errors returns the errors that occurred while sending key events since the
+// creation of the KBWrap or the last call of ResetErrors.
+// Unless Stubborn is set, no further keys are pressed as long as there are errors.
+func (kb *KBWrap) Errors() []error {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+
+	return append([]error(nil), kb.errors...)
+}
+
+// ResetErrors clears the accumulated errors, so that keys are pressed again.
+func (kb *KBWrap) ResetErrors() {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+
+	kb.errors = []error{}
+}
+
 func (kb *KBWrap) check() bool {
 	if kb.stubborn {
 		return true
@@ -20,7 +93,11 @@ func (kb *KBWrap) handle(err error) {
 		return
 	}
 	kb.errors = append(kb.errors, err)
-	if kb.noisy {
-		println(err.Error())
+	kb.stats.Errors++
+	if kb.logger != nil {
+		kb.logger.LogAttrs(context.Background(), slog.LevelError, "key event failed", slog.Any("err", err))
+	}
+	if kb.onError != nil {
+		kb.onError(err)
 	}
 }
@@ -0,0 +1,126 @@
+package sendkeys
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+var errBroken = errors.New("broken")
+
+// failingBackend fails to press the given key.
+type failingBackend struct {
+	*Recorder
+	key KeyCode
+}
+
+func (b *failingBackend) Press(key KeyCode) error {
+	if key == b.key {
+		return errBroken
+	}
+	return b.Recorder.Press(key)
+}
+
+func newFailingKBWrap(t *testing.T, opts ...KBOpt) (*KBWrap, *Recorder) {
+	t.Helper()
+	rec := NewRecorder()
+	opts = append([]KBOpt{
+		WithBackend(&failingBackend{Recorder: rec, key: SimpleKeyCode(48)}),
+		WithKeyMap(KeyMap{'a': SimpleKeyCode(30), 'b': SimpleKeyCode(48), 'c': SimpleKeyCode(46)}),
+		KeystrokeDuration(0),
+		DelayAfter(0),
+	}, opts...)
+	k, err := NewKBWrapWithOptions(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k, rec
+}
+
+func TestTypeKeystrokeError(t *testing.T) {
+	var handled []error
+	k, rec := newFailingKBWrap(t, OnError(func(err error) {
+		handled = append(handled, err)
+	}))
+
+	err := k.Type("abc")
+	var kerr *KeystrokeError
+	if !errors.As(err, &kerr) {
+		t.Fatalf("expected *KeystrokeError, got %v", err)
+	}
+	if kerr.Index != 1 || kerr.Rune != 'b' || kerr.KeyCode != SimpleKeyCode(48) || kerr.Phase != PhaseDown {
+		t.Errorf("unexpected error context: %+v", kerr)
+	}
+	if !errors.Is(err, errBroken) {
+		t.Errorf("expected the error of the backend, got %v", err)
+	}
+	if got := kerr.Error(); got != `key down b of 'b' at index 1: broken` {
+		t.Errorf("unexpected message: %s", got)
+	}
+
+	// typing stops at the first error
+	if got := len(rec.Events()); got != 3 {
+		t.Errorf("expected 3 events, got %d: %v", got, rec.Events())
+	}
+	if len(handled) != 1 || handled[0] != kerr {
+		t.Errorf("expected the error to be handled once, got %v", handled)
+	}
+
+	if got := k.Errors(); len(got) != 1 || got[0] != kerr {
+		t.Errorf("expected the accumulated error, got %v", got)
+	}
+	err = k.Type("a")
+	if err == nil {
+		t.Fatal("expected the accumulated error to prevent typing")
+	}
+
+	k.ResetErrors()
+	if got := k.Errors(); len(got) != 0 {
+		t.Errorf("expected no errors after reset, got %v", got)
+	}
+	err = k.Type("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTypeKeystrokeErrorStubborn(t *testing.T) {
+	k, rec := newFailingKBWrap(t, Stubborn)
+
+	n, err := k.TypeContext(context.Background(), "abcb")
+	if n != 4 {
+		t.Errorf("expected 4 characters, got %d", n)
+	}
+	var indexes []int
+	for _, err := range k.Errors() {
+		var kerr *KeystrokeError
+		if errors.As(err, &kerr) {
+			indexes = append(indexes, kerr.Index)
+		}
+	}
+	if len(indexes) != 2 || indexes[0] != 1 || indexes[1] != 3 {
+		t.Errorf("expected errors at index 1 and 3, got %v", indexes)
+	}
+	if !errors.Is(err, errBroken) {
+		t.Errorf("expected the error of the backend, got %v", err)
+	}
+	if got := len(rec.Events()); got != 6 {
+		t.Errorf("expected 6 events, got %d: %v", got, rec.Events())
+	}
+}
+
+func TestKeystrokeErrorWithoutCharacter(t *testing.T) {
+	k, _ := newFailingKBWrap(t)
+
+	err := k.KeyDown(SimpleKeyCode(48))
+	var kerr *KeystrokeError
+	if !errors.As(err, &kerr) {
+		t.Fatalf("expected *KeystrokeError, got %v", err)
+	}
+	if kerr.Index != -1 || kerr.Rune != 0 {
+		t.Errorf("unexpected error context: %+v", kerr)
+	}
+	if got := kerr.Error(); got != "key down b: broken" {
+		t.Errorf("unexpected message: %s", got)
+	}
+}
@@ -0,0 +1,63 @@
+package sendkeys
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Fallback creates the key events for characters that are neither part of the
+// KeyMap nor of the KeySequences, e.g. emoji.
+type Fallback interface {
+	Events(r rune, keyMap KeyMap) ([]KeyEvent, error)
+}
+
+// FallbackFunc is a function that implements the Fallback interface.
+type FallbackFunc func(r rune, keyMap KeyMap) ([]KeyEvent, error)
+
+func (f FallbackFunc) Events(r rune, keyMap KeyMap) ([]KeyEvent, error) {
+	return f(r, keyMap)
+}
+
+// UnicodeHexInput types characters as Ctrl+Shift+U followed by the hexadecimal
+// code point and Space, which is supported by GTK applications and IBus.
+// The hex digits and the space are looked up in the KeyMap.
+func UnicodeHexInput() Fallback {
+	return FallbackFunc(func(r rune, keyMap KeyMap) ([]KeyEvent, error) {
+		u, ok := keyMap['u']
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrKeyMappingNotFound, 'u')
+		}
+		u.ModifierCTRL = true
+		u.ModifierSHIFT = true
+
+		keys := []KeyCode{u}
+		for _, c := range strconv.FormatInt(int64(r), 16) + " " {
+			code, ok := keyMap[c]
+			if !ok {
+				return nil, fmt.Errorf("%w: %q", ErrKeyMappingNotFound, c)
+			}
+			keys = append(keys, code)
+		}
+		return Tap(keys...), nil
+	})
+}
+
+// AltNumpadInput types characters by holding Alt while typing the decimal
+// code point on the numeric keypad, which is supported by Windows applications.
+// Code points below 256 are prefixed with a 0 in order to select the Unicode
+// character instead of the OEM code page character.
+func AltNumpadInput() Fallback {
+	return FallbackFunc(func(r rune, _ KeyMap) ([]KeyEvent, error) {
+		digits := strconv.Itoa(int(r))
+		if r < 256 {
+			digits = "0" + digits
+		}
+
+		alt := SimpleKeyCode(altKey)
+		events := []KeyEvent{KeyDownEvent(alt)}
+		for _, d := range digits {
+			events = append(events, Tap(SimpleKeyCode(numpadKeys[d-'0']))...)
+		}
+		return append(events, KeyUpEvent(alt)), nil
+	})
+}
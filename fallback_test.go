@@ -0,0 +1,71 @@
+package sendkeys
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestUnicodeHexInput(t *testing.T) {
+	keyMap := KeyMapLinuxQuartz()
+	k, rec := newRecordingKBWrap(t, WithKeyMap(keyMap), WithFallback(UnicodeHexInput()))
+
+	err := k.Type("a😀")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u := keyMap['u']
+	u.ModifierCTRL = true
+	u.ModifierSHIFT = true
+	expected := []KeyCode{keyMap['a'], u}
+	for _, c := range "1f600 " {
+		expected = append(expected, keyMap[c])
+	}
+
+	keys := rec.Keys()
+	if !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("expected %v, got %v", expected, keys)
+	}
+}
+
+func TestAltNumpadInput(t *testing.T) {
+	k, rec := newRecordingKBWrap(t, WithKeyMap(KeyMap{}), WithFallback(AltNumpadInput()))
+
+	err := k.Type("é")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alt := SimpleKeyCode(altKey)
+	expected := []KeyEvent{KeyDownEvent(alt)}
+	for _, d := range "0233" {
+		expected = append(expected, Tap(SimpleKeyCode(numpadKeys[d-'0']))...)
+	}
+	expected = append(expected, KeyUpEvent(alt))
+
+	events := rec.Events()
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %d", len(expected), len(events))
+	}
+	for i, e := range events {
+		if e.Down != expected[i].Down || e.Key != expected[i].Key {
+			t.Fatalf("event %d: expected %v, got %v", i, expected[i], e)
+		}
+	}
+}
+
+func TestFallbackFuncError(t *testing.T) {
+	errNope := errors.New("nope")
+	k, rec := newRecordingKBWrap(t, WithKeyMap(KeyMap{}), WithFallback(FallbackFunc(func(r rune, _ KeyMap) ([]KeyEvent, error) {
+		return nil, errNope
+	})))
+
+	err := k.Type("x")
+	if !errors.Is(err, ErrKeyMappingNotFound) || !errors.Is(err, errNope) {
+		t.Fatalf("expected both errors, got %v", err)
+	}
+	if len(rec.Events()) != 0 {
+		t.Fatalf("expected no events, got %v", rec.Events())
+	}
+}
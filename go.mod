@@ -1,14 +1,16 @@
 module github.com/jxsl13/sendkeys
 
-go 1.18
+go 1.21
 
 require (
 	github.com/eiannone/keyboard v0.0.0-20220611211555-0d226195f203
+	github.com/manifoldco/promptui v0.9.0
 	github.com/micmonay/keybd_event v1.1.2
+	golang.org/x/text v0.14.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/chzyer/readline v0.0.0-20180603132655-2972be24d48e // indirect
-	github.com/manifoldco/promptui v0.9.0 // indirect
 	golang.org/x/sys v0.20.0 // indirect
 )
@@ -0,0 +1,143 @@
+package sendkeys
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// hold marks the key as pressed.
+func (kb *KBWrap) hold(key KeyCode) {
+	for _, k := range kb.held {
+		if k == key {
+			return
+		}
+	}
+	kb.held = append(kb.held, key)
+}
+
+// unhold marks the key as released.
+func (kb *KBWrap) unhold(key KeyCode) {
+	for i, k := range kb.held {
+		if k == key {
+			kb.held = append(kb.held[:i], kb.held[i+1:]...)
+			return
+		}
+	}
+}
+
+// withoutHeldModifiers removes the modifiers from the key that are already held down
+// by modifier only keys, e.g. by KeyDown or {HOLD SHIFT} in scripts. Otherwise, the
+// backend would release the held modifiers together with the key.
+func (kb *KBWrap) withoutHeldModifiers(key KeyCode) KeyCode {
+	if key.ModifiersOnly() {
+		return key
+	}
+	for _, k := range kb.held {
+		if !k.ModifiersOnly() {
+			continue
+		}
+		key.ModifierSuper = key.ModifierSuper && !k.ModifierSuper
+		key.ModifierALT = key.ModifierALT && !k.ModifierALT
+		key.ModifierCTRL = key.ModifierCTRL && !k.ModifierCTRL
+		key.ModifierSHIFT = key.ModifierSHIFT && !k.ModifierSHIFT
+		key.ModifierALTGR = key.ModifierALTGR && !k.ModifierALTGR
+	}
+	if !key.hasModifiers() {
+		key.ModifierSide = SideAny
+	}
+	return key
+}
+
+// releaseAll releases all held keys in reverse order.
+func (kb *KBWrap) releaseAll() error {
+	var errs []error
+	for i := len(kb.held) - 1; i >= 0; i-- {
+		errs = append(errs, kb.releaseKey(kb.held[i]))
+	}
+	kb.held = nil
+	return errors.Join(errs...)
+}
+
+// releaseOnPanic releases all held keys in case of a panic and continues panicking.
+// It must be deferred.
+func (kb *KBWrap) releaseOnPanic() {
+	if r := recover(); r != nil {
+		_ = kb.releaseAll()
+		panic(r)
+	}
+}
+
+// Held returns the keys that are currently held down, in the order they were pressed.
+func (kb *KBWrap) Held() []KeyCode {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+
+	return append([]KeyCode(nil), kb.held...)
+}
+
+// KeyDown presses the given key with its modifiers and keeps it pressed
+// until KeyUp, ReleaseAll or Close is called.
+// Pressing a key that is already held down has no effect.
+func (kb *KBWrap) KeyDown(key KeyCode) error {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	defer kb.releaseOnPanic()
+
+	for _, k := range kb.held {
+		if k == key {
+			return nil
+		}
+	}
+	err := kb.pressKey(key)
+	if err != nil {
+		return err
+	}
+	kb.hold(key)
+	return nil
+}
+
+// KeyUp releases the given key with its modifiers.
+func (kb *KBWrap) KeyUp(key KeyCode) error {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+
+	kb.unhold(key)
+	return kb.releaseKey(key)
+}
+
+// ReleaseAll releases all keys that are currently held down in reverse order.
+func (kb *KBWrap) ReleaseAll() error {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+
+	return kb.releaseAll()
+}
+
+// Hold presses the given key, keeps it pressed for the given duration and releases it,
+// e.g. in order to trigger the auto repeat of the key.
+func (kb *KBWrap) Hold(key KeyCode, d time.Duration) error {
+	return kb.HoldContext(context.Background(), key, d)
+}
+
+// HoldContext presses the given key for the given duration or until the context is cancelled.
+// The key is always released.
+func (kb *KBWrap) HoldContext(ctx context.Context, key KeyCode, d time.Duration) (err error) {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	defer kb.releaseOnPanic()
+
+	err = ctx.Err()
+	if err != nil {
+		return err
+	}
+	err = kb.pressKey(key)
+	if err != nil {
+		return err
+	}
+	kb.hold(key)
+
+	err = kb.sleep(ctx, d)
+	kb.unhold(key)
+	return errors.Join(err, kb.releaseKey(key))
+}
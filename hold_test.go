@@ -0,0 +1,102 @@
+package sendkeys
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestKeyDownReleaseAll(t *testing.T) {
+	k, rec := newRecordingKBWrap(t)
+	shift := ShiftKeyCode(42)
+	a := SimpleKeyCode(30)
+
+	for _, key := range []KeyCode{shift, a, a} {
+		err := k.KeyDown(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if held := k.Held(); !reflect.DeepEqual(held, []KeyCode{shift, a}) {
+		t.Fatalf("unexpected held keys: %v", held)
+	}
+
+	err := k.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if held := k.Held(); len(held) != 0 {
+		t.Fatalf("expected no held keys, got %v", held)
+	}
+
+	expected := []KeyEvent{KeyDownEvent(shift), KeyDownEvent(a), KeyUpEvent(a), KeyUpEvent(shift)}
+	events := rec.Events()
+	if len(events) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, events)
+	}
+	for i, e := range events {
+		if e.Down != expected[i].Down || e.Key != expected[i].Key {
+			t.Fatalf("event %d: expected %v, got %v", i, expected[i], e)
+		}
+	}
+}
+
+func TestHold(t *testing.T) {
+	k, rec := newRecordingKBWrap(t)
+	key := SimpleKeyCode(103)
+
+	err := k.Hold(key, 20*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := rec.Events()
+	if len(events) != 2 || !events[0].Down || events[1].Down {
+		t.Fatalf("unexpected events: %v", events)
+	}
+	if d := events[1].Time.Sub(events[0].Time); d < 20*time.Millisecond {
+		t.Fatalf("key was held for %s only", d)
+	}
+	if held := k.Held(); len(held) != 0 {
+		t.Fatalf("expected no held keys, got %v", held)
+	}
+}
+
+type panicBackend struct {
+	*Recorder
+	n int
+}
+
+func (b *panicBackend) Press(key KeyCode) error {
+	b.n++
+	if b.n > 1 {
+		panic("boom")
+	}
+	return b.Recorder.Press(key)
+}
+
+func TestReleaseOnPanic(t *testing.T) {
+	rec := NewRecorder()
+	k, err := NewKBWrapWithOptions(
+		WithBackend(&panicBackend{Recorder: rec}),
+		WithKeyMap(KeyMap{'a': SimpleKeyCode(30), 'b': SimpleKeyCode(48)}),
+		KeystrokeDuration(0),
+		DelayAfter(0),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected panic")
+			}
+		}()
+		_ = k.Chord("a+b")
+	}()
+
+	events := rec.Events()
+	if len(events) != 2 || !events[0].Down || events[1].Down {
+		t.Fatalf("expected the pressed key to be released, got %v", events)
+	}
+}
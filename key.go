@@ -0,0 +1,252 @@
+package sendkeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrKeyNotSupported is returned when a named Key does not exist on the current platform.
+var ErrKeyNotSupported = errors.New("key not supported on this platform")
+
+// Key is a named special key, e.g. an arrow or a function key.
+// It is resolved to the key code of the current platform.
+type Key int
+
+const (
+	KeyEscape Key = iota + 1
+	KeyTab
+	KeyEnter
+	KeyBackSpace
+	KeySpace
+	KeyCapsLock
+	KeyPrintScreen
+	KeyScrollLock
+	KeyPause
+	KeyMenu
+
+	KeyInsert
+	KeyDelete
+	KeyHome
+	KeyEnd
+	KeyPageUp
+	KeyPageDown
+
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+
+	KeyF1
+	KeyF2
+	KeyF3
+	KeyF4
+	KeyF5
+	KeyF6
+	KeyF7
+	KeyF8
+	KeyF9
+	KeyF10
+	KeyF11
+	KeyF12
+	KeyF13
+	KeyF14
+	KeyF15
+	KeyF16
+	KeyF17
+	KeyF18
+	KeyF19
+	KeyF20
+	KeyF21
+	KeyF22
+	KeyF23
+	KeyF24
+
+	KeyNumLock
+	KeyNumpad0
+	KeyNumpad1
+	KeyNumpad2
+	KeyNumpad3
+	KeyNumpad4
+	KeyNumpad5
+	KeyNumpad6
+	KeyNumpad7
+	KeyNumpad8
+	KeyNumpad9
+	KeyNumpadAdd
+	KeyNumpadSubtract
+	KeyNumpadMultiply
+	KeyNumpadDivide
+	KeyNumpadDecimal
+	KeyNumpadEnter
+	KeyNumpadEqual
+
+	KeyVolumeMute
+	KeyVolumeDown
+	KeyVolumeUp
+	KeyMediaPlayPause
+	KeyMediaStop
+	KeyMediaNext
+	KeyMediaPrevious
+)
+
+var keyNames = map[Key]string{
+	KeyEscape:      "Escape",
+	KeyTab:         "Tab",
+	KeyEnter:       "Enter",
+	KeyBackSpace:   "BackSpace",
+	KeySpace:       "Space",
+	KeyCapsLock:    "CapsLock",
+	KeyPrintScreen: "PrintScreen",
+	KeyScrollLock:  "ScrollLock",
+	KeyPause:       "Pause",
+	KeyMenu:        "Menu",
+
+	KeyInsert:   "Insert",
+	KeyDelete:   "Delete",
+	KeyHome:     "Home",
+	KeyEnd:      "End",
+	KeyPageUp:   "PageUp",
+	KeyPageDown: "PageDown",
+
+	KeyUp:    "Up",
+	KeyDown:  "Down",
+	KeyLeft:  "Left",
+	KeyRight: "Right",
+
+	KeyNumLock:        "NumLock",
+	KeyNumpadAdd:      "NumpadAdd",
+	KeyNumpadSubtract: "NumpadSubtract",
+	KeyNumpadMultiply: "NumpadMultiply",
+	KeyNumpadDivide:   "NumpadDivide",
+	KeyNumpadDecimal:  "NumpadDecimal",
+	KeyNumpadEnter:    "NumpadEnter",
+	KeyNumpadEqual:    "NumpadEqual",
+
+	KeyVolumeMute:     "VolumeMute",
+	KeyVolumeDown:     "VolumeDown",
+	KeyVolumeUp:       "VolumeUp",
+	KeyMediaPlayPause: "MediaPlayPause",
+	KeyMediaStop:      "MediaStop",
+	KeyMediaNext:      "MediaNext",
+	KeyMediaPrevious:  "MediaPrevious",
+}
+
+func init() {
+	for i := 0; i < 24; i++ {
+		keyNames[KeyF1+Key(i)] = "F" + strconv.Itoa(i+1)
+	}
+	for i := 0; i < 10; i++ {
+		keyNames[KeyNumpad0+Key(i)] = "Numpad" + strconv.Itoa(i)
+	}
+}
+
+// platformKeyNames maps the key codes of the current platform back to the named keys.
+var platformKeyNames = func() map[int]Key {
+	result := make(map[int]Key, len(platformKeys))
+	for key, code := range platformKeys {
+		if k, ok := result[code]; !ok || key < k {
+			result[code] = key
+		}
+	}
+	return result
+}()
+
+// keyAliases are alternative names of keys which are accepted by ParseKey.
+var keyAliases = map[string]Key{
+	"esc":       KeyEscape,
+	"return":    KeyEnter,
+	"bksp":      KeyBackSpace,
+	"del":       KeyDelete,
+	"ins":       KeyInsert,
+	"pgup":      KeyPageUp,
+	"pgdn":      KeyPageDown,
+	"prtsc":     KeyPrintScreen,
+	"print":     KeyPrintScreen,
+	"apps":      KeyMenu,
+	"break":     KeyPause,
+	"mute":      KeyVolumeMute,
+	"playpause": KeyMediaPlayPause,
+}
+
+// ParseKey returns the named key, e.g. "F1", "PageDown" or "esc".
+// Names are case insensitive.
+func ParseKey(name string) (Key, error) {
+	name = strings.ToLower(name)
+	if key, ok := keyAliases[name]; ok {
+		return key, nil
+	}
+	for key, n := range keyNames {
+		if strings.ToLower(n) == name {
+			return key, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: unknown key: %q", ErrKeyNotSupported, name)
+}
+
+func (k Key) String() string {
+	if name, ok := keyNames[k]; ok {
+		return name
+	}
+	return "Key(" + strconv.Itoa(int(k)) + ")"
+}
+
+// KeyCode resolves the key to the key code of the current platform
+// and applies the given modifiers.
+func (k Key) KeyCode(mods ...Modifier) (KeyCode, error) {
+	code, ok := platformKeys[k]
+	if !ok {
+		return KeyCode{}, fmt.Errorf("%w: %v", ErrKeyNotSupported, k)
+	}
+	key := SimpleKeyCode(code)
+	for _, m := range mods {
+		m.apply(&key)
+	}
+	return key, nil
+}
+
+// Modifier is a modifier key that is held down while another key is pressed.
+type Modifier int
+
+const (
+	ModShift Modifier = iota + 1
+	ModCtrl
+	ModAlt   // Alt/Option
+	ModAltGr // AltGr/ISO_Level3_Shift
+	ModSuper // WIN/CMD/MOD
+)
+
+func (m Modifier) apply(key *KeyCode) {
+	switch m {
+	case ModShift:
+		key.ModifierSHIFT = true
+	case ModCtrl:
+		key.ModifierCTRL = true
+	case ModAlt:
+		key.ModifierALT = true
+	case ModAltGr:
+		key.ModifierALTGR = true
+	case ModSuper:
+		key.ModifierSuper = true
+	}
+}
+
+// Press presses the named key while holding the given modifiers.
+func (kb *KBWrap) Press(key Key, mods ...Modifier) error {
+	return kb.PressContext(context.Background(), key, mods...)
+}
+
+// PressContext presses the named key while holding the given modifiers
+// unless the context is cancelled.
+func (kb *KBWrap) PressContext(ctx context.Context, key Key, mods ...Modifier) error {
+	code, err := kb.namedKey(key)
+	if err != nil {
+		return err
+	}
+	for _, m := range mods {
+		m.apply(&code)
+	}
+	return kb.TypeRawContext(ctx, code)
+}
@@ -0,0 +1,44 @@
+package sendkeys
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestKeyString(t *testing.T) {
+	for k, expected := range map[Key]string{
+		KeyF1:          "F1",
+		KeyF24:         "F24",
+		KeyNumpad0:     "Numpad0",
+		KeyNumpad9:     "Numpad9",
+		KeyPageDown:    "PageDown",
+		KeyMediaNext:   "MediaNext",
+		Key(0):         "Key(0)",
+		KeyPrintScreen: "PrintScreen",
+	} {
+		if k.String() != expected {
+			t.Errorf("expected %q, got %q", expected, k.String())
+		}
+	}
+}
+
+func TestPress(t *testing.T) {
+	k, rec := newRecordingKBWrap(t)
+
+	err := k.Press(KeyLeft, ModCtrl, ModShift)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := KeyCode{Code: platformKeys[KeyLeft], ModifierCTRL: true, ModifierSHIFT: true}
+	keys := rec.Keys()
+	if !reflect.DeepEqual(keys, []KeyCode{expected}) {
+		t.Fatalf("expected %v, got %v", expected, keys)
+	}
+
+	err = k.Press(Key(0))
+	if !errors.Is(err, ErrKeyNotSupported) {
+		t.Fatalf("expected ErrKeyNotSupported, got %v", err)
+	}
+}
@@ -1,20 +1,174 @@
 package sendkeys
 
-import "encoding/json"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Side selects whether the left or the right modifier keys are pressed.
+type Side uint8
+
+const (
+	SideAny Side = iota // platform default
+	SideLeft
+	SideRight
+)
+
+func (s Side) String() string {
+	switch s {
+	case SideAny:
+		return ""
+	case SideLeft:
+		return "left"
+	case SideRight:
+		return "right"
+	default:
+		return "Side(" + strconv.Itoa(int(s)) + ")"
+	}
+}
+
+func (s Side) MarshalText() ([]byte, error) {
+	if s > SideRight {
+		return nil, fmt.Errorf("invalid modifier side: %d", s)
+	}
+	return []byte(s.String()), nil
+}
+
+func (s *Side) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "":
+		*s = SideAny
+	case "left":
+		*s = SideLeft
+	case "right":
+		*s = SideRight
+	default:
+		return fmt.Errorf("invalid modifier side: %q", text)
+	}
+	return nil
+}
 
 type KeyCode struct {
-	Code          int  `json:"code"`
-	ModifierSuper bool `json:"super"` // WIN/CMD/MOD
-	ModifierALT   bool `json:"alt"`   // Alt/Option
-	ModifierCTRL  bool `json:"ctrl"`
-	ModifierSHIFT bool `json:"shift"`
+	Code          int  `json:"code" yaml:"code"`
+	ModifierSuper bool `json:"super" yaml:"super"` // WIN/CMD/MOD
+	ModifierALT   bool `json:"alt" yaml:"alt"`     // Alt/Option
+	ModifierCTRL  bool `json:"ctrl" yaml:"ctrl"`
+	ModifierSHIFT bool `json:"shift" yaml:"shift"`
+	ModifierALTGR bool `json:"altgr,omitempty" yaml:"altgr,omitempty"` // AltGr/ISO_Level3_Shift
+	ModifierSide  Side `json:"side,omitempty" yaml:"side,omitempty"`   // left or right modifier keys
+	// X11 keysym that is sent by keysym based backends instead of the key code, see KeysymMap
+	Keysym uint32 `json:"keysym,omitempty" yaml:"keysym,omitempty"`
 }
 
+// ModifiersOnly reports whether the key code presses modifier keys without any other key,
+// e.g. in order to hold Shift down while other keys are pressed.
+func (k KeyCode) ModifiersOnly() bool {
+	return k.Code == 0 && k.Keysym == 0 && k.hasModifiers()
+}
+
+// hasModifiers reports whether any modifier is set.
+func (k KeyCode) hasModifiers() bool {
+	return k.ModifierSuper || k.ModifierALT || k.ModifierCTRL || k.ModifierSHIFT || k.ModifierALTGR
+}
+
+// String returns the human readable form of the key code, e.g. "ctrl+shift+escape"
+// or "lalt+a", which can be parsed by ParseChord.
+// Named keys of the current platform are rendered by name, keys that type a character
+// without modifiers in the default KeyMap as the character and all other key codes in hex.
+// Keysyms are rendered as their named key or character, e.g. "ctrl+c" in keysym mode,
+// see WithKeysymMap, or as "keysym:0x20ac" otherwise.
+// Key codes without a key are rendered as their modifiers, e.g. "lshift".
 func (k KeyCode) String() string {
-	data, _ := json.Marshal(k)
-	return string(data)
+	var sb strings.Builder
+	prefix := ""
+	switch k.ModifierSide {
+	case SideLeft:
+		prefix = "l"
+	case SideRight:
+		prefix = "r"
+	}
+	for _, m := range []struct {
+		ok   bool
+		name string
+	}{
+		{k.ModifierSuper, prefix + "super"},
+		{k.ModifierCTRL, prefix + "ctrl"},
+		{k.ModifierALT, prefix + "alt"},
+		{k.ModifierALTGR, "altgr"},
+		{k.ModifierSHIFT, prefix + "shift"},
+	} {
+		if m.ok {
+			sb.WriteString(m.name)
+			sb.WriteByte('+')
+		}
+	}
+	if k.ModifiersOnly() {
+		return strings.TrimSuffix(sb.String(), "+")
+	}
+	if k.Code == 0 && k.Keysym != 0 {
+		sb.WriteString(keysymName(k.Keysym))
+		return sb.String()
+	}
+	sb.WriteString(keyCodeName(k.Code))
+	return sb.String()
+}
+
+// keyCodeName returns the lower case name of a named key, the character that the key
+// types in the default KeyMap or the hex key code.
+func keyCodeName(code int) string {
+	if key, ok := platformKeyNames[code]; ok {
+		return strings.ToLower(key.String())
+	}
+	if r, ok := keyMapRunes[code]; ok {
+		return chordRune(r)
+	}
+	return "0x" + strconv.FormatInt(int64(code), 16)
 }
 
+// keysymName returns the lower case name of a named key, the character of the keysym
+// or the hex keysym.
+func keysymName(ks uint32) string {
+	if key, ok := keysymKeys[ks]; ok {
+		return strings.ToLower(key.String())
+	}
+	if r, ok := keysymToRune(ks); ok && chordChar(r) {
+		return chordRune(r)
+	}
+	return "keysym:0x" + strconv.FormatUint(uint64(ks), 16)
+}
+
+// chordChar reports whether the character can be parsed as key of a chord,
+// which looks up the lower case character.
+func chordChar(r rune) bool {
+	return unicode.IsGraphic(r) && !unicode.IsSpace(r) && unicode.ToLower(r) == r
+}
+
+// chordRune returns the character as key of a chord.
+func chordRune(r rune) string {
+	if r == '+' {
+		return "plus"
+	}
+	return string(r)
+}
+
+// keyMapRunes maps the key codes of the default KeyMap back to the characters that they
+// type without modifiers, as ParseChord looks up characters in the default KeyMap.
+// The smallest character of a key code wins.
+var keyMapRunes = func() map[int]rune {
+	result := map[int]rune{}
+	for r, key := range defaultKeyMap() {
+		if key != SimpleKeyCode(key.Code) || !chordChar(r) {
+			continue
+		}
+		if prev, ok := result[key.Code]; !ok || r < prev {
+			result[key.Code] = r
+		}
+	}
+	return result
+}()
+
 func SimpleKeyCode(code int) KeyCode {
 	return KeyCode{
 		Code: code,
@@ -42,3 +196,18 @@ func AltShiftKeyCode(code int) KeyCode {
 		ModifierSHIFT: true,
 	}
 }
+
+func AltGrKeyCode(code int) KeyCode {
+	return KeyCode{
+		Code:          code,
+		ModifierALTGR: true,
+	}
+}
+
+func AltGrShiftKeyCode(code int) KeyCode {
+	return KeyCode{
+		Code:          code,
+		ModifierALTGR: true,
+		ModifierSHIFT: true,
+	}
+}
@@ -0,0 +1,36 @@
+package sendkeys
+
+import "time"
+
+// KeyEvent is a single key down or key up event.
+type KeyEvent struct {
+	Down bool      `json:"down"`
+	Key  KeyCode   `json:"key"`
+	Time time.Time `json:"time"`
+}
+
+func (e KeyEvent) String() string {
+	if e.Down {
+		return "down " + e.Key.String()
+	}
+	return "up   " + e.Key.String()
+}
+
+// KeyDownEvent creates a key down event.
+func KeyDownEvent(key KeyCode) KeyEvent {
+	return KeyEvent{Down: true, Key: key}
+}
+
+// KeyUpEvent creates a key up event.
+func KeyUpEvent(key KeyCode) KeyEvent {
+	return KeyEvent{Down: false, Key: key}
+}
+
+// Tap creates the key events that press and release every key one after another.
+func Tap(keys ...KeyCode) []KeyEvent {
+	events := make([]KeyEvent, 0, 2*len(keys))
+	for _, key := range keys {
+		events = append(events, KeyDownEvent(key), KeyUpEvent(key))
+	}
+	return events
+}
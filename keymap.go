@@ -1,9 +1,6 @@
 package sendkeys
 
-import (
-	"encoding/json"
-	"fmt"
-)
+import "encoding/json"
 
 type KeyMap map[rune]KeyCode
 
@@ -12,6 +9,19 @@ func (k KeyMap) String() string {
 	return string(data)
 }
 
+// Reverse returns the inverse mapping from key codes to characters.
+// In case multiple characters share the same key code, the smallest one is used.
+func (k KeyMap) Reverse() map[KeyCode]rune {
+	result := make(map[KeyCode]rune, len(k))
+	for r, code := range k {
+		if prev, ok := result[code]; ok && prev < r {
+			continue
+		}
+		result[code] = r
+	}
+	return result
+}
+
 // https://chromium.googlesource.com/chromium/chromium/+/18a10fbde23dd76184d9be2a892c628b5cae3da1/ui/keyboard/resources/elements/kb-key-codes.html
 // I use this for my KVM switch
 func KeyMap_US_EN101() KeyMap {
@@ -329,18 +339,3 @@ func KeyMapDarwin_DE_QWERTZ() KeyMap {
 		':':  ShiftKeyCode(47),
 	}
 }
-
-func (kb *KBWrap) strToKeys(str string) (keys []KeyCode) {
-	for _, r := range str {
-		code, ok := kb.keyMap[r]
-		if ok {
-			keys = append(keys, code)
-		} else {
-			kb.errors = append(
-				kb.errors,
-				fmt.Errorf("%w: %v", ErrKeyMappingNotFound, r),
-			)
-		}
-	}
-	return
-}
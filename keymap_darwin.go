@@ -3,3 +3,7 @@ package sendkeys
 func defaultKeyMap() KeyMap {
 	return KeyMapDarwin_DE_QWERTZ()
 }
+
+func defaultKeySequences() KeySequences {
+	return KeySequencesDarwin_DE_QWERTZ()
+}
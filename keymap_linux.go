@@ -3,3 +3,7 @@ package sendkeys
 func defaultKeyMap() KeyMap {
 	return KeyMapLinuxQuartz()
 }
+
+func defaultKeySequences() KeySequences {
+	return KeySequences{}
+}
@@ -0,0 +1,295 @@
+package sendkeys
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// KeyMapFileVersion is the current version of the key map file format.
+const KeyMapFileVersion = 1
+
+// MaxKeyCode is the largest key code that is accepted in key map files.
+// It is the largest key code of all supported platforms (linux evdev KEY_MAX).
+const MaxKeyCode = 0x2FF
+
+var (
+	// ErrInvalidKeyMapFile is returned when a key map file cannot be decoded or validated.
+	ErrInvalidKeyMapFile = errors.New("invalid key map file")
+)
+
+// KeyMapFile is the serialized form of a KeyMap and its KeySequences.
+// Files that only contain the keys object without any header,
+// as printed by older versions of cmd/keycode-collector, are accepted as well.
+type KeyMapFile struct {
+	Version   int                  `json:"version" yaml:"version"`
+	Layout    string               `json:"layout,omitempty" yaml:"layout,omitempty"`
+	Platform  string               `json:"platform,omitempty" yaml:"platform,omitempty"`
+	Keys      map[string]KeyCode   `json:"keys" yaml:"keys"`
+	Sequences map[string][]KeyCode `json:"sequences,omitempty" yaml:"sequences,omitempty"`
+}
+
+// NewKeyMapFile creates the serializable form of the given KeyMap.
+func NewKeyMapFile(keyMap KeyMap, layout, platform string) *KeyMapFile {
+	keys := make(map[string]KeyCode, len(keyMap))
+	for r, code := range keyMap {
+		keys[string(r)] = code
+	}
+	return &KeyMapFile{
+		Version:  KeyMapFileVersion,
+		Layout:   layout,
+		Platform: platform,
+		Keys:     keys,
+	}
+}
+
+// SetKeySequences adds the given key sequences to the file.
+func (f *KeyMapFile) SetKeySequences(sequences KeySequences) {
+	f.Sequences = make(map[string][]KeyCode, len(sequences))
+	for r, seq := range sequences {
+		f.Sequences[string(r)] = seq
+	}
+}
+
+// DecodeKeyMapFile reads a JSON or YAML key map file.
+func DecodeKeyMapFile(r io.Reader) (*KeyMapFile, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is a subset of YAML, which is why a single decoder suffices.
+	// In contrast to encoding/json, duplicate keys are rejected by yaml.
+	var header struct {
+		Version *int `yaml:"version"`
+	}
+	err = yaml.Unmarshal(data, &header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKeyMapFile, err)
+	}
+
+	f := &KeyMapFile{}
+	if header.Version == nil {
+		// legacy format without header
+		f.Version = KeyMapFileVersion
+		err = yaml.Unmarshal(data, &f.Keys)
+	} else {
+		err = yaml.Unmarshal(data, f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKeyMapFile, err)
+	}
+
+	if f.Version != KeyMapFileVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d, expected %d", ErrInvalidKeyMapFile, f.Version, KeyMapFileVersion)
+	}
+	return f, nil
+}
+
+// KeyMap validates the file and converts it into a KeyMap.
+func (f *KeyMapFile) KeyMap() (KeyMap, error) {
+	var (
+		result = make(KeyMap, len(f.Keys))
+		codes  = make(map[KeyCode]string, len(f.Keys))
+		errs   []error
+	)
+
+	for _, key := range sortedKeys(f.Keys) {
+		code := f.Keys[key]
+		r, err := parseKeyMapFileKey(key)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		err = validateKeyMapFileCode(key, code)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if other, ok := codes[code]; ok {
+			errs = append(errs, fmt.Errorf("%w: keys %q and %q share the same key code %s", ErrInvalidKeyMapFile, other, key, code))
+			continue
+		}
+		codes[code] = key
+		result[r] = code
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return result, nil
+}
+
+// KeySequences validates the sequences of the file and converts them into KeySequences.
+func (f *KeyMapFile) KeySequences() (KeySequences, error) {
+	var (
+		result = make(KeySequences, len(f.Sequences))
+		errs   []error
+	)
+
+	for _, key := range sortedKeys(f.Sequences) {
+		seq := f.Sequences[key]
+		r, err := parseKeyMapFileKey(key)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if len(seq) == 0 {
+			errs = append(errs, fmt.Errorf("%w: sequence %q must not be empty", ErrInvalidKeyMapFile, key))
+			continue
+		}
+		for _, code := range seq {
+			err = validateKeyMapFileCode(key, code)
+			if err != nil {
+				errs = append(errs, err)
+				break
+			}
+		}
+		if err != nil {
+			continue
+		}
+		result[r] = seq
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return result, nil
+}
+
+func parseKeyMapFileKey(key string) (rune, error) {
+	if utf8.RuneCountInString(key) != 1 {
+		return 0, fmt.Errorf("%w: key %q must be exactly one character", ErrInvalidKeyMapFile, key)
+	}
+	r, _ := utf8.DecodeRuneInString(key)
+	if r == utf8.RuneError {
+		return 0, fmt.Errorf("%w: key %q is not valid utf-8", ErrInvalidKeyMapFile, key)
+	}
+	return r, nil
+}
+
+func validateKeyMapFileCode(key string, code KeyCode) error {
+	if code.Code < 0 || code.Code > MaxKeyCode {
+		return fmt.Errorf("%w: key %q: code %d out of range [0, %d]", ErrInvalidKeyMapFile, key, code.Code, MaxKeyCode)
+	}
+	return nil
+}
+
+// Encode writes the key map file as indented JSON.
+func (f *KeyMapFile) Encode(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", " ")
+	return enc.Encode(f)
+}
+
+// EncodeYAML writes the key map file as YAML.
+func (f *KeyMapFile) EncodeYAML(w io.Writer) error {
+	// the yaml encoder does not preserve keys like "\n",
+	// which is why the keys are double quoted explicitly.
+	keys := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, key := range sortedKeys(f.Keys) {
+		var value yaml.Node
+		err := value.Encode(f.Keys[key])
+		if err != nil {
+			return err
+		}
+		keys.Content = append(keys.Content, quotedYAMLKey(key), &value)
+	}
+
+	header := *f
+	header.Keys = nil
+	header.Sequences = nil
+
+	var node yaml.Node
+	err := node.Encode(header)
+	if err != nil {
+		return err
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "keys" {
+			node.Content[i+1] = keys
+		}
+	}
+	if len(f.Sequences) > 0 {
+		sequences := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, key := range sortedKeys(f.Sequences) {
+			var value yaml.Node
+			err := value.Encode(f.Sequences[key])
+			if err != nil {
+				return err
+			}
+			sequences.Content = append(sequences.Content, quotedYAMLKey(key), &value)
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "sequences"}, sequences)
+	}
+
+	enc := yaml.NewEncoder(w)
+	defer enc.Close()
+	return enc.Encode(&node)
+}
+
+func quotedYAMLKey(key string) *yaml.Node {
+	return &yaml.Node{
+		Kind:  yaml.ScalarNode,
+		Tag:   "!!str",
+		Style: yaml.DoubleQuotedStyle,
+		Value: key,
+	}
+}
+
+// LoadKeyMap reads and validates a JSON or YAML key map file.
+func LoadKeyMap(r io.Reader) (KeyMap, error) {
+	f, err := DecodeKeyMapFile(r)
+	if err != nil {
+		return nil, err
+	}
+	return f.KeyMap()
+}
+
+// LoadKeyMapFile reads and validates the key map file at the given path.
+func LoadKeyMapFile(path string) (KeyMap, error) {
+	f, err := openKeyMapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	keyMap, err := f.KeyMap()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return keyMap, nil
+}
+
+func openKeyMapFile(path string) (*KeyMapFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	f, err := DecodeKeyMapFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Save writes the KeyMap as versioned JSON key map file for the current platform.
+func (k KeyMap) Save(w io.Writer) error {
+	return NewKeyMapFile(k, "", runtime.GOOS).Encode(w)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,115 @@
+package sendkeys
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKeyMapSaveLoad(t *testing.T) {
+	keyMaps := map[string]KeyMap{
+		"KeyMap_US_EN101":        KeyMap_US_EN101(),
+		"KeyMapLinuxQuartz":      KeyMapLinuxQuartz(),
+		"KeyMapDarwin_DE_QWERTZ": KeyMapDarwin_DE_QWERTZ(),
+	}
+
+	for name, keyMap := range keyMaps {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := keyMap.Save(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			loaded, err := LoadKeyMap(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if loaded.String() != keyMap.String() {
+				t.Fatalf("loaded key map differs:\n%s\nexpected:\n%s", loaded, keyMap)
+			}
+
+			buf.Reset()
+			err = NewKeyMapFile(keyMap, "test", "linux").EncodeYAML(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := DecodeKeyMapFile(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.Layout != "test" || f.Platform != "linux" {
+				t.Fatalf("unexpected header: %#v", f)
+			}
+			loaded, err = f.KeyMap()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if loaded.String() != keyMap.String() {
+				t.Fatalf("loaded yaml key map differs:\n%s\nexpected:\n%s", loaded, keyMap)
+			}
+		})
+	}
+}
+
+func TestLoadKeyMapLegacy(t *testing.T) {
+	// output format of cmd/keycode-collector before the versioned header was introduced
+	legacy := `{
+ "a": {"code": 30, "super": false, "alt": false, "ctrl": false, "shift": false},
+ "A": {"code": 30, "super": false, "alt": false, "ctrl": false, "shift": true}
+}`
+	keyMap, err := LoadKeyMap(strings.NewReader(legacy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyMap['a'] != SimpleKeyCode(30) || keyMap['A'] != ShiftKeyCode(30) {
+		t.Fatalf("unexpected key map: %s", keyMap)
+	}
+}
+
+func TestLoadKeyMapInvalid(t *testing.T) {
+	tests := map[string]string{
+		"multiple runes":   `{"version": 1, "keys": {"ab": {"code": 1}}}`,
+		"empty key":        `{"version": 1, "keys": {"": {"code": 1}}}`,
+		"duplicate key":    `{"version": 1, "keys": {"a": {"code": 1}, "a": {"code": 2}}}`,
+		"duplicate code":   `{"version": 1, "keys": {"a": {"code": 1}, "b": {"code": 1}}}`,
+		"negative code":    `{"version": 1, "keys": {"a": {"code": -1}}}`,
+		"code too large":   `{"version": 1, "keys": {"a": {"code": 4096}}}`,
+		"unknown version":  `{"version": 2, "keys": {"a": {"code": 1}}}`,
+		"malformed":        `{"version": 1, "keys": {"a": `,
+		"invalid modifier": `{"version": 1, "keys": {"a": {"code": 1, "shift": "maybe"}}}`,
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadKeyMap(strings.NewReader(data))
+			if !errors.Is(err, ErrInvalidKeyMapFile) {
+				t.Fatalf("expected %v, got %v", ErrInvalidKeyMapFile, err)
+			}
+		})
+	}
+}
+
+func TestWithKeyMapFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keymap.yaml")
+	err := os.WriteFile(path, []byte("version: 1\nlayout: us\nkeys:\n  x: {code: 45}\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k, err := NewKBWrapWithOptions(WithBackend(NewRecorder()), WithKeyMapFile(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(k.KeyMap()) != 1 || k.KeyMap()['x'] != SimpleKeyCode(45) {
+		t.Fatalf("unexpected key map: %s", k.KeyMap())
+	}
+
+	_, err = NewKBWrapWithOptions(WithBackend(NewRecorder()), WithKeyMapFile(path+".missing"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected %v, got %v", os.ErrNotExist, err)
+	}
+}
@@ -0,0 +1,77 @@
+package sendkeys
+
+import (
+	"encoding/json"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// KeySequences maps characters to the sequence of key codes that has to be typed
+// in order to produce the character, e.g. a dead key followed by a base key or
+// a compose sequence.
+// Entries in KeySequences take precedence over entries in the KeyMap.
+type KeySequences map[rune][]KeyCode
+
+func (s KeySequences) String() string {
+	data, _ := json.MarshalIndent(s, "", " ")
+	return string(data)
+}
+
+// DeadKey is a key that does not produce a character on its own
+// but modifies the character of the following key.
+type DeadKey struct {
+	// Key is the key code of the dead key.
+	Key KeyCode
+	// Spacing is the character that is produced when the dead key is followed by space, e.g. '´'.
+	Spacing rune
+	// Mark is the combining character of the dead key, e.g. U+0301 (combining acute accent).
+	Mark rune
+}
+
+// DeadKeySequences creates the key sequences of the given dead keys for all
+// characters that can be composed with a base character of the KeyMap.
+// The spacing variant of a dead key is typed as dead key followed by space.
+func DeadKeySequences(keyMap KeyMap, space KeyCode, deadKeys ...DeadKey) KeySequences {
+	result := KeySequences{}
+	for _, dk := range deadKeys {
+		if dk.Spacing != 0 {
+			result[dk.Spacing] = []KeyCode{dk.Key, space}
+		}
+		if dk.Mark == 0 {
+			continue
+		}
+		for base, code := range keyMap {
+			if !unicode.IsLetter(base) {
+				continue
+			}
+			composed := norm.NFC.String(string(base) + string(dk.Mark))
+			r, size := utf8.DecodeRuneInString(composed)
+			if size != len(composed) || r == base {
+				// no precomposed character
+				continue
+			}
+			if _, ok := keyMap[r]; ok {
+				// directly typeable
+				continue
+			}
+			result[r] = []KeyCode{dk.Key, code}
+		}
+	}
+	return result
+}
+
+// KeySequencesDarwin_DE_QWERTZ contains the dead keys of the german macOS layout.
+// It is meant to be used together with KeyMapDarwin_DE_QWERTZ.
+func KeySequencesDarwin_DE_QWERTZ() KeySequences {
+	return DeadKeySequences(
+		KeyMapDarwin_DE_QWERTZ(),
+		SimpleKeyCode(49),
+		DeadKey{Key: SimpleKeyCode(24), Spacing: '´', Mark: '\u0301'},
+		DeadKey{Key: ShiftKeyCode(24), Spacing: '`', Mark: '\u0300'},
+		DeadKey{Key: SimpleKeyCode(10), Spacing: '^', Mark: '\u0302'},
+		DeadKey{Key: AltKeyCode(45), Spacing: '~', Mark: '\u0303'},
+		DeadKey{Key: AltKeyCode(32), Spacing: '¨', Mark: '\u0308'},
+	)
+}
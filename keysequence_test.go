@@ -0,0 +1,104 @@
+package sendkeys
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestKeySequencesDarwin_DE_QWERTZ(t *testing.T) {
+	sequences := KeySequencesDarwin_DE_QWERTZ()
+
+	tests := map[rune][]KeyCode{
+		'´': {SimpleKeyCode(24), SimpleKeyCode(49)},
+		'^': {SimpleKeyCode(10), SimpleKeyCode(49)},
+		'é': {SimpleKeyCode(24), SimpleKeyCode(14)},
+		'È': {ShiftKeyCode(24), ShiftKeyCode(14)},
+		'ñ': {AltKeyCode(45), SimpleKeyCode(45)},
+		'ü': {AltKeyCode(32), SimpleKeyCode(32)},
+	}
+	for r, expected := range tests {
+		seq := sequences[r]
+		if len(seq) != len(expected) {
+			t.Errorf("%q: expected %v, got %v", r, expected, seq)
+			continue
+		}
+		for i := range seq {
+			if seq[i] != expected[i] {
+				t.Errorf("%q: expected %v, got %v", r, expected, seq)
+				break
+			}
+		}
+	}
+}
+
+func TestTypeKeySequences(t *testing.T) {
+	var (
+		keyMap    = KeyMapDarwin_DE_QWERTZ()
+		sequences = KeySequencesDarwin_DE_QWERTZ()
+	)
+	k, rec := newRecordingKBWrap(t, WithKeyMap(keyMap), WithKeySequences(sequences))
+
+	teststr := "Café crème, señor ^_^ `´"
+	n, err := k.TypeContext(context.Background(), teststr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len([]rune(teststr)) {
+		t.Fatalf("expected %d typed characters, got %d", len([]rune(teststr)), n)
+	}
+
+	// é, è, ñ, ^, ^, ` and ´ need two keys
+	expected := 2 * (len([]rune(teststr)) + 7)
+	if len(rec.Events()) != expected {
+		t.Fatalf("expected %d events, got %d", expected, len(rec.Events()))
+	}
+
+	typed, err := rec.TextWithSequences(keyMap, sequences)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typed != teststr {
+		t.Fatalf("[FAIL] Have: %q, Wanted: %q", typed, teststr)
+	}
+}
+
+func TestKeyMapFileSequences(t *testing.T) {
+	sequences := KeySequences{
+		'é':  {SimpleKeyCode(24), SimpleKeyCode(14)},
+		'\n': {SimpleKeyCode(36)},
+	}
+	f := NewKeyMapFile(KeyMap{'e': SimpleKeyCode(14)}, "de", "darwin")
+	f.SetKeySequences(sequences)
+
+	for name, encode := range map[string]func(*bytes.Buffer) error{
+		"json": func(b *bytes.Buffer) error { return f.Encode(b) },
+		"yaml": func(b *bytes.Buffer) error { return f.EncodeYAML(b) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := encode(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decoded, err := DecodeKeyMapFile(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := decoded.KeySequences()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if loaded.String() != sequences.String() {
+				t.Fatalf("loaded sequences differ:\n%s\nexpected:\n%s", loaded, sequences)
+			}
+		})
+	}
+
+	f.Sequences["x"] = nil
+	_, err := f.KeySequences()
+	if err == nil {
+		t.Fatal("expected error for empty sequence")
+	}
+}
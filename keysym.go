@@ -0,0 +1,188 @@
+package sendkeys
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+//go:generate go run keysym_gen.go
+
+// keysymToRune returns the Unicode character that is produced by the given X11 keysym.
+func keysymToRune(ks uint32) (rune, bool) {
+	switch {
+	case ks >= 0x20 && ks <= 0x7e, ks >= 0xa0 && ks <= 0xff:
+		return rune(ks), true
+	case ks >= 0x01000100 && ks <= 0x0110ffff:
+		return rune(ks - 0x01000000), true
+	}
+	r, ok := keysymUnicode[ks]
+	return r, ok
+}
+
+// parseKeysym parses a keysym name like "adiaeresis", "U20AC" or "0x1001E9E".
+func parseKeysym(name string) (uint32, bool) {
+	if ks, ok := keysymNames[name]; ok {
+		return ks, true
+	}
+	switch {
+	case strings.HasPrefix(name, "0x"):
+		ks, err := strconv.ParseUint(name[2:], 16, 32)
+		return uint32(ks), err == nil
+	case len(name) > 1 && name[0] == 'U':
+		r, err := strconv.ParseUint(name[1:], 16, 32)
+		if err != nil || r > 0x10ffff {
+			return 0, false
+		}
+		if r < 0x100 {
+			return uint32(r), true
+		}
+		return uint32(r) + 0x01000000, true
+	}
+	return 0, false
+}
+
+// KeysymMap maps characters to X11 keysyms, which are sent by keysym based backends
+// like the RFBBackend instead of key codes. The target translates the keysyms
+// according to its own keyboard layout, so no layout specific KeyMap is needed.
+// Characters that are not part of the map are translated with RuneKeysym.
+type KeysymMap map[rune]uint32
+
+// Keysym returns the keysym of the character.
+func (m KeysymMap) Keysym(r rune) (uint32, bool) {
+	if ks, ok := m[r]; ok {
+		return ks, true
+	}
+	return RuneKeysym(r)
+}
+
+// KeyMap returns a KeyMap of all printable ASCII and Latin-1 characters, line feed,
+// tab and the characters of the map, whose key codes only consist of the keysym.
+func (m KeysymMap) KeyMap() KeyMap {
+	keyMap := KeyMap{}
+	add := func(r rune) {
+		if ks, ok := m.Keysym(r); ok {
+			keyMap[r] = KeysymKeyCode(ks)
+		}
+	}
+	for r := rune(0x20); r <= 0xff; r++ {
+		add(r)
+	}
+	add('\n')
+	add('\t')
+	for r := range m {
+		add(r)
+	}
+	return keyMap
+}
+
+// controlKeysyms are the keysyms of the keys that produce control characters.
+var controlKeysyms = map[rune]uint32{
+	'\b':   0xff08, // BackSpace
+	'\t':   0xff09, // Tab
+	'\n':   0xff0d, // Return
+	'\r':   0xff0d, // Return
+	'\x1b': 0xff1b, // Escape
+	'\x7f': 0xffff, // Delete
+}
+
+// runeKeysyms maps characters to their legacy keysyms, which are understood by
+// more targets than the Unicode keysyms. The smallest keysym of a character wins.
+var runeKeysyms = func() map[rune]uint32 {
+	result := make(map[rune]uint32, len(keysymUnicode))
+	for ks, r := range keysymUnicode {
+		if prev, ok := result[r]; !ok || ks < prev {
+			result[r] = ks
+		}
+	}
+	return result
+}()
+
+// RuneKeysym returns the X11 keysym of a character. Latin-1 characters and characters
+// with a legacy keysym use it, all other characters use the Unicode keysym 0x01000000 + r.
+func RuneKeysym(r rune) (uint32, bool) {
+	switch {
+	case r >= 0x20 && r <= 0x7e, r >= 0xa0 && r <= 0xff:
+		return uint32(r), true
+	case r < 0x20, r >= 0x7f && r < 0xa0:
+		ks, ok := controlKeysyms[r]
+		return ks, ok
+	case r > unicode.MaxRune, r >= 0xd800 && r <= 0xdfff:
+		return 0, false
+	}
+	if ks, ok := runeKeysyms[r]; ok {
+		return ks, true
+	}
+	return 0x01000000 + uint32(r), true
+}
+
+// KeysymKeyCode creates a key code that only consists of the X11 keysym.
+func KeysymKeyCode(ks uint32) KeyCode {
+	return KeyCode{Keysym: ks}
+}
+
+// keyKeysyms maps the named keys to X11 keysyms.
+var keyKeysyms = map[Key]uint32{
+	KeyEscape:      0xff1b,
+	KeyTab:         0xff09,
+	KeyEnter:       0xff0d,
+	KeyBackSpace:   0xff08,
+	KeySpace:       0x0020,
+	KeyCapsLock:    0xffe5,
+	KeyPrintScreen: 0xff61,
+	KeyScrollLock:  0xff14,
+	KeyPause:       0xff13,
+	KeyMenu:        0xff67,
+
+	KeyInsert:   0xff63,
+	KeyDelete:   0xffff,
+	KeyHome:     0xff50,
+	KeyEnd:      0xff57,
+	KeyPageUp:   0xff55,
+	KeyPageDown: 0xff56,
+
+	KeyUp:    0xff52,
+	KeyDown:  0xff54,
+	KeyLeft:  0xff51,
+	KeyRight: 0xff53,
+
+	KeyNumLock:        0xff7f,
+	KeyNumpadAdd:      0xffab,
+	KeyNumpadSubtract: 0xffad,
+	KeyNumpadMultiply: 0xffaa,
+	KeyNumpadDivide:   0xffaf,
+	KeyNumpadDecimal:  0xffae,
+	KeyNumpadEnter:    0xff8d,
+	KeyNumpadEqual:    0xffbd,
+
+	KeyVolumeMute:     0x1008ff12,
+	KeyVolumeDown:     0x1008ff11,
+	KeyVolumeUp:       0x1008ff13,
+	KeyMediaPlayPause: 0x1008ff14,
+	KeyMediaStop:      0x1008ff15,
+	KeyMediaNext:      0x1008ff17,
+	KeyMediaPrevious:  0x1008ff16,
+}
+
+func init() {
+	for i := 0; i < 24; i++ {
+		keyKeysyms[KeyF1+Key(i)] = 0xffbe + uint32(i)
+	}
+	for i := 0; i < 10; i++ {
+		keyKeysyms[KeyNumpad0+Key(i)] = 0xffb0 + uint32(i)
+	}
+	for key, ks := range keyKeysyms {
+		if prev, ok := keysymKeys[ks]; !ok || key < prev {
+			keysymKeys[ks] = key
+		}
+	}
+}
+
+// keysymKeys maps keysyms back to the named keys. The smallest key of a keysym wins.
+var keysymKeys = map[uint32]Key{}
+
+// Keysym returns the X11 keysym of the named key.
+func (k Key) Keysym() (uint32, bool) {
+	ks, ok := keyKeysyms[k]
+	return ks, ok
+}
@@ -0,0 +1,94 @@
+package sendkeys
+
+// evdevKeysyms maps linux evdev key codes, which are the key codes of KeyMapLinuxQuartz,
+// to the X11 keysyms of the unshifted keys of a US keyboard.
+var evdevKeysyms = map[int]uint32{
+	// printable keys
+	2: '1', 3: '2', 4: '3', 5: '4', 6: '5', 7: '6', 8: '7', 9: '8', 10: '9', 11: '0', 12: '-', 13: '=',
+	16: 'q', 17: 'w', 18: 'e', 19: 'r', 20: 't', 21: 'y', 22: 'u', 23: 'i', 24: 'o', 25: 'p', 26: '[', 27: ']',
+	30: 'a', 31: 's', 32: 'd', 33: 'f', 34: 'g', 35: 'h', 36: 'j', 37: 'k', 38: 'l', 39: ';', 40: '\'', 41: '`',
+	43: '\\', 44: 'z', 45: 'x', 46: 'c', 47: 'v', 48: 'b', 49: 'n', 50: 'm', 51: ',', 52: '.', 53: '/',
+	57: ' ', 86: '<',
+
+	// editing keys: Escape, BackSpace, Tab, Return, Insert, Delete, Home, End, Prior, Next
+	1: 0xff1b, 14: 0xff08, 15: 0xff09, 28: 0xff0d, 110: 0xff63, 111: 0xffff,
+	102: 0xff50, 107: 0xff57, 104: 0xff55, 109: 0xff56,
+
+	// cursor keys: Up, Left, Right, Down
+	103: 0xff52, 105: 0xff51, 106: 0xff53, 108: 0xff54,
+
+	// modifiers: Control_L, Shift_L, Shift_R, Alt_L, Control_R, Alt_R, Super_L, Super_R
+	29: 0xffe3, 42: 0xffe1, 54: 0xffe2, 56: 0xffe9, 97: 0xffe4, 100: 0xffea, 125: 0xffeb, 126: 0xffec,
+
+	// locks and system keys: Caps_Lock, Num_Lock, Scroll_Lock, Print, Pause, Menu
+	58: 0xffe5, 69: 0xff7f, 70: 0xff14, 99: 0xff61, 119: 0xff13, 127: 0xff67,
+
+	// function keys F1 - F24
+	59: 0xffbe, 60: 0xffbf, 61: 0xffc0, 62: 0xffc1, 63: 0xffc2, 64: 0xffc3,
+	65: 0xffc4, 66: 0xffc5, 67: 0xffc6, 68: 0xffc7, 87: 0xffc8, 88: 0xffc9,
+	183: 0xffca, 184: 0xffcb, 185: 0xffcc, 186: 0xffcd, 187: 0xffce, 188: 0xffcf,
+	189: 0xffd0, 190: 0xffd1, 191: 0xffd2, 192: 0xffd3, 193: 0xffd4, 194: 0xffd5,
+
+	// numeric keypad: KP_0 - KP_9, KP_Multiply, KP_Subtract, KP_Add, KP_Decimal, KP_Enter, KP_Divide, KP_Equal
+	82: 0xffb0, 79: 0xffb1, 80: 0xffb2, 81: 0xffb3, 75: 0xffb4, 76: 0xffb5, 77: 0xffb6, 71: 0xffb7, 72: 0xffb8, 73: 0xffb9,
+	55: 0xffaa, 74: 0xffad, 78: 0xffab, 83: 0xffae, 96: 0xff8d, 98: 0xffaf, 117: 0xffbd,
+
+	// media keys: XF86AudioMute, XF86AudioLowerVolume, XF86AudioRaiseVolume,
+	// XF86AudioNext, XF86AudioPlay, XF86AudioPrev, XF86AudioStop
+	113: 0x1008ff12, 114: 0x1008ff11, 115: 0x1008ff13,
+	163: 0x1008ff17, 164: 0x1008ff14, 165: 0x1008ff16, 166: 0x1008ff15,
+}
+
+// keysymEvdev maps X11 keysyms back to the evdev key codes that produce them
+// without modifiers. The smallest key code of a keysym wins.
+var keysymEvdev = func() map[uint32]int {
+	result := make(map[uint32]int, len(evdevKeysyms))
+	for code, ks := range evdevKeysyms {
+		if prev, ok := result[ks]; !ok || code < prev {
+			result[ks] = code
+		}
+	}
+	return result
+}()
+
+// EvdevKeysym returns the X11 keysym of a linux evdev key code
+// according to the unshifted keys of a US keyboard.
+func EvdevKeysym(code int) (uint32, bool) {
+	ks, ok := evdevKeysyms[code]
+	return ks, ok
+}
+
+// modifier keysyms in the order super, ctrl, alt, shift
+var (
+	leftModifierKeysyms  = []uint32{0xffeb, 0xffe3, 0xffe9, 0xffe1}
+	rightModifierKeysyms = []uint32{0xffec, 0xffe4, 0xffea, 0xffe2}
+)
+
+// keysymISOLevel3Shift is the keysym of AltGr.
+const keysymISOLevel3Shift = 0xfe03
+
+// modifierKeysyms returns the keysyms of the modifiers of the key in the order
+// in which they are pressed: super, ctrl, alt, altgr, shift.
+func modifierKeysyms(key KeyCode) []uint32 {
+	keysyms := leftModifierKeysyms
+	if key.ModifierSide == SideRight {
+		keysyms = rightModifierKeysyms
+	}
+	var result []uint32
+	if key.ModifierSuper {
+		result = append(result, keysyms[0])
+	}
+	if key.ModifierCTRL {
+		result = append(result, keysyms[1])
+	}
+	if key.ModifierALT {
+		result = append(result, keysyms[2])
+	}
+	if key.ModifierALTGR {
+		result = append(result, keysymISOLevel3Shift)
+	}
+	if key.ModifierSHIFT {
+		result = append(result, keysyms[3])
+	}
+	return result
+}
@@ -0,0 +1,117 @@
+package sendkeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestRuneKeysym(t *testing.T) {
+	for r, expected := range map[rune]uint32{
+		'a':    0x61,
+		'A':    0x41,
+		'ä':    0xe4,
+		'\n':   0xff0d,
+		'\t':   0xff09,
+		'€':    0x20ac,     // EuroSign
+		'ā':    0x03e0,     // amacron
+		'あ':    0x01003042, // Unicode keysym
+		'😀':    0x0101f600,
+		'\x01': 0,
+	} {
+		ks, ok := RuneKeysym(r)
+		if ks != expected || ok != (expected != 0) {
+			t.Errorf("%q: expected %#x, got %#x", r, expected, ks)
+		}
+		if !ok || r < 0x20 {
+			continue
+		}
+		back, ok := keysymToRune(ks)
+		if !ok || back != r {
+			t.Errorf("%q: keysym %#x translates back to %q", r, ks, back)
+		}
+	}
+}
+
+func TestKeysymMap(t *testing.T) {
+	m := KeysymMap{'ß': 0xdf, '→': 0x8fd}
+	if ks, _ := m.Keysym('→'); ks != 0x8fd {
+		t.Errorf("expected the keysym of the map, got %#x", ks)
+	}
+	keyMap := m.KeyMap()
+	for r, expected := range map[rune]KeyCode{
+		'a':  {Keysym: 'a'},
+		'~':  {Keysym: '~'},
+		'ÿ':  {Keysym: 0xff},
+		'\n': {Keysym: 0xff0d},
+		'→':  {Keysym: 0x8fd},
+	} {
+		if keyMap[r] != expected {
+			t.Errorf("%q: expected %v, got %v", r, expected, keyMap[r])
+		}
+	}
+	if _, ok := keyMap['あ']; ok {
+		t.Error("expected only ASCII, Latin-1 and the characters of the map")
+	}
+	if s := KeysymKeyCode(0x20ac).String(); s != "€" {
+		t.Errorf("unexpected string: %s", s)
+	}
+}
+
+func TestWithKeysymMap(t *testing.T) {
+	k, rec := newRecordingKBWrap(t, WithKeysymMap(nil))
+
+	text := "Hi €あ\n"
+	err := k.Type(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = errors.Join(k.Press(KeyF5, ModCtrl), k.Chord("ctrl+c", "ctrl+a+b"), k.EnterContext(context.Background()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := rec.Keys()
+	expected := []KeyCode{
+		{Keysym: 'H'}, {Keysym: 'i'}, {Keysym: ' '}, {Keysym: 0x20ac}, {Keysym: 0x01003042}, {Keysym: 0xff0d},
+		{Keysym: 0xffc2, ModifierCTRL: true},
+		{Keysym: 'c', ModifierCTRL: true},
+		{Keysym: 'a', ModifierCTRL: true}, {Keysym: 'b'},
+		{Keysym: 0xff0d},
+	}
+	if fmt.Sprint(keys) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, keys)
+	}
+
+	rec.Reset()
+	_ = k.Type(text)
+	got, err := rec.Text(k.KeyMap())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != text {
+		t.Errorf("expected %q, got %q", text, got)
+	}
+}
+
+func TestKeysymMapRFB(t *testing.T) {
+	server, addr := newRFBServer(t, "RFB 003.008\n", "")
+	b, err := DialRFB(context.Background(), addr, RFBConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := NewKBWrapWithOptions(WithBackend(b), WithKeysymMap(nil), KeystrokeDuration(0), DelayAfter(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = errors.Join(k.Type("Ü€"), k.Close())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []rfbKey{{true, 0xdc}, {false, 0xdc}, {true, 0x20ac}, {false, 0x20ac}}
+	if keys := server.Keys(); fmt.Sprint(keys) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, keys)
+	}
+}
@@ -0,0 +1,77 @@
+package sendkeys
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// WithLogger logs every key event, delay and character translation at debug level
+// and every error at error level with the given logger.
+func WithLogger(logger *slog.Logger) KBOpt {
+	return func(k *KBWrap) {
+		k.logger = logger
+	}
+}
+
+// OnKeyEvent calls f for every key event that was sent, e.g. in order to show progress
+// or to keep an audit trail. f is called while the KBWrap is locked and must not use it.
+func OnKeyEvent(f func(e KeyEvent)) KBOpt {
+	return func(k *KBWrap) {
+		k.onKeyEvent = f
+	}
+}
+
+// debug logs a debug message with the character that is currently typed.
+func (kb *KBWrap) debug(msg string, attrs ...slog.Attr) {
+	if kb.logger == nil {
+		return
+	}
+	ctx := context.Background()
+	if !kb.logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	if kb.char != nil {
+		attrs = append(attrs, slog.String("char", string(kb.char.Rune)), slog.Int("index", kb.char.Index))
+	}
+	kb.logger.LogAttrs(ctx, slog.LevelDebug, msg, attrs...)
+}
+
+// sent reports a key event that was sent successfully.
+func (kb *KBWrap) sent(down bool, key KeyCode) {
+	if down {
+		kb.stats.Keys++
+		kb.debug("key down", slog.String("key", key.String()))
+	} else {
+		kb.debug("key up", slog.String("key", key.String()))
+	}
+	if kb.onKeyEvent != nil {
+		kb.onKeyEvent(KeyEvent{Down: down, Key: key, Time: time.Now()})
+	}
+}
+
+// delay waits for a delay of the given phase of a keystroke
+// and adds the time spent waiting to the statistics.
+func (kb *KBWrap) delay(ctx context.Context, phase delayPhase, d time.Duration) error {
+	kb.debug("delay", slog.String("phase", phase.String()), slog.Duration("duration", d))
+	start, clock := time.Now(), kb.clock
+	err := kb.sleep(ctx, d)
+	if kb.dryRun != nil {
+		kb.stats.spent(phase, kb.clock-clock)
+	} else {
+		kb.stats.spent(phase, time.Since(start))
+	}
+	return err
+}
+
+// eventKeys returns the pressed keys of the events, separated by spaces.
+func eventKeys(events []KeyEvent) string {
+	var keys []string
+	for _, e := range events {
+		if e.Down {
+			keys = append(keys, e.Key.String())
+		}
+	}
+	return strings.Join(keys, " ")
+}
@@ -0,0 +1,89 @@
+package sendkeys
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var r map[string]any
+		err := json.Unmarshal([]byte(line), &r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestWithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	k, _ := newRecordingKBWrap(t,
+		WithKeyMap(KeyMap{'a': SimpleKeyCode(30)}),
+		WithLogger(logger),
+	)
+
+	err := k.Type("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var msgs []string
+	for _, r := range logRecords(t, &buf) {
+		msg := r["msg"].(string)
+		if msg == "delay" {
+			msg += " " + r["phase"].(string)
+		}
+		msgs = append(msgs, msg)
+		if msg != "resolved" && (r["char"] != "a" || r["index"] != 0.0) {
+			t.Errorf("expected the typed character: %v", r)
+		}
+	}
+	expected := "resolved,delay before,key down,delay down,key up,delay after"
+	if got := strings.Join(msgs, ","); got != expected {
+		t.Errorf("expected %s, got %s", expected, got)
+	}
+}
+
+func TestLoggerErrors(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	k, _ := newFailingKBWrap(t, WithLogger(logger))
+
+	err := k.Type("b")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	records := logRecords(t, &buf)
+	if len(records) != 1 || records[0]["level"] != "ERROR" || records[0]["err"] != err.Error() {
+		t.Errorf("expected the error to be logged, got %s", buf.String())
+	}
+}
+
+func TestOnKeyEvent(t *testing.T) {
+	var events []KeyEvent
+	k, _ := newFailingKBWrap(t, Stubborn, OnKeyEvent(func(e KeyEvent) {
+		events = append(events, e)
+	}))
+
+	_ = k.Type("ab")
+	// the failed key down event of 'b' is not reported
+	expected := "down a,up   a,up   b"
+	var got []string
+	for _, e := range events {
+		if e.Time.IsZero() {
+			t.Errorf("expected the time of %s", e)
+		}
+		got = append(got, e.String())
+	}
+	if strings.Join(got, ",") != expected {
+		t.Errorf("expected %s, got %s", expected, strings.Join(got, ","))
+	}
+}
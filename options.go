@@ -1,6 +1,7 @@
 package sendkeys
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -13,13 +14,23 @@ func Stubborn(o *KBWrap) {
 	o.stubborn = true
 }
 
-// Noisy will cause all errors to be printed to stdout.
+// Noisy will cause all errors to be logged with the default slog.Logger,
+// unless a logger is set with WithLogger.
 func Noisy(o *KBWrap) {
 	o.noisy = true
 }
 
+// OnError calls f for every error that occurs while sending key events,
+// e.g. a *KeystrokeError. f is called while the KBWrap is locked and must not use it.
+func OnError(f func(err error)) KBOpt {
+	return func(k *KBWrap) {
+		k.onError = f
+	}
+}
+
 // Random will use random sleeps throughout the typing process.
-// Otherwise, a static 10 milliseconds will be used.
+// All configured delays are varied by up to 50%.
+// Otherwise, the configured delays are used as they are.
 func Random(o *KBWrap) {
 	o.random = true
 }
@@ -31,25 +42,132 @@ func NoDelay(o *KBWrap) {
 
 // Delay allows to change the delay between keystrokes
 func DelayBefore(delay time.Duration) KBOpt {
+	return WithBeforeDelay(FixedDelay(delay))
+}
+
+// KeystrokeDuration changes how long a key is held down.
+func KeystrokeDuration(d time.Duration) KBOpt {
+	return WithDownDelay(FixedDelay(d))
+}
+
+// DelayAfter changes how long to wait after a key was released.
+func DelayAfter(d time.Duration) KBOpt {
+	return WithAfterDelay(FixedDelay(d))
+}
+
+// WithBeforeDelay sets the Delay that is used before a key is pressed.
+func WithBeforeDelay(d Delay) KBOpt {
 	return func(k *KBWrap) {
-		k.beforeDuration = delay
+		k.beforeDelay = d
 	}
 }
 
-func KeystrokeDuration(d time.Duration) KBOpt {
+// WithDownDelay sets the Delay that determines how long a key is held down.
+func WithDownDelay(d Delay) KBOpt {
 	return func(k *KBWrap) {
-		k.beforeDuration = d
+		k.downDelay = d
 	}
 }
 
-func DelayAfter(d time.Duration) KBOpt {
+// WithAfterDelay sets the Delay that is used after a key was released.
+func WithAfterDelay(d Delay) KBOpt {
+	return func(k *KBWrap) {
+		k.afterDelay = d
+	}
+}
+
+// WithSeed seeds the random number generator that is used by the delays,
+// which makes randomized typing reproducible.
+func WithSeed(seed int64) KBOpt {
 	return func(k *KBWrap) {
-		k.afterDuration = d
+		k.rng = newRand(seed)
 	}
 }
 
+// WithKeyMap sets the KeyMap that translates characters into key codes.
+// The default key sequences of the platform are not used with a custom KeyMap.
 func WithKeyMap(keyMap KeyMap) KBOpt {
 	return func(k *KBWrap) {
 		k.keyMap = keyMap
 	}
 }
+
+// WithKeySequences sets the sequences of key codes for characters that need more than one key,
+// e.g. dead keys or compose sequences.
+func WithKeySequences(sequences KeySequences) KBOpt {
+	return func(k *KBWrap) {
+		k.sequences = sequences
+	}
+}
+
+// WithFallback sets the Fallback that is used in order to type characters
+// which are not part of the KeyMap, e.g. UnicodeHexInput or AltNumpadInput.
+// It sets the UnmappedPolicy to UnmappedFallback.
+func WithFallback(f Fallback) KBOpt {
+	return func(k *KBWrap) {
+		k.fallback = f
+		k.policy = UnmappedFallback
+	}
+}
+
+// WithUnmappedPolicy sets how characters are handled that cannot be typed.
+// The default is UnmappedAbort.
+func WithUnmappedPolicy(p UnmappedPolicy) KBOpt {
+	return func(k *KBWrap) {
+		k.policy = p
+	}
+}
+
+// WithSubstitute types the given character instead of characters that cannot be typed.
+// It sets the UnmappedPolicy to UnmappedSubstitute.
+func WithSubstitute(r rune) KBOpt {
+	return func(k *KBWrap) {
+		k.substitute = r
+		k.policy = UnmappedSubstitute
+	}
+}
+
+// WithKeyMapFile loads the KeyMap and the KeySequences from a JSON or YAML key map file.
+// Errors are returned by NewKBWrapWithOptions.
+func WithKeyMapFile(path string) KBOpt {
+	return func(k *KBWrap) {
+		f, err := openKeyMapFile(path)
+		if err != nil {
+			k.handle(err)
+			return
+		}
+		keyMap, err := f.KeyMap()
+		if err != nil {
+			k.handle(fmt.Errorf("%s: %w", path, err))
+			return
+		}
+		sequences, err := f.KeySequences()
+		if err != nil {
+			k.handle(fmt.Errorf("%s: %w", path, err))
+			return
+		}
+		k.keyMap = keyMap
+		k.sequences = sequences
+	}
+}
+
+// WithKeysymMap sends X11 keysyms instead of key codes, which requires a keysym based
+// Backend like the RFBBackend. Unless a KeyMap is set, every character is translated
+// with the KeysymMap, see KeysymMap.KeyMap. Named keys are sent as keysyms as well.
+// The default Backend returns ErrKeysymNotSupported for keysyms.
+func WithKeysymMap(m KeysymMap) KBOpt {
+	return func(k *KBWrap) {
+		if m == nil {
+			m = KeysymMap{}
+		}
+		k.keysyms = m
+	}
+}
+
+// WithBackend allows to replace the default keybd_event Backend,
+// e.g. in order to send key events to a remote machine.
+func WithBackend(b Backend) KBOpt {
+	return func(k *KBWrap) {
+		k.d = b
+	}
+}
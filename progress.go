@@ -0,0 +1,150 @@
+package sendkeys
+
+import (
+	"context"
+	"time"
+)
+
+// Progress is the state of a Type call after a character was sent.
+type Progress struct {
+	Sent          int           // characters sent so far
+	Total         int           // characters that are typed in total
+	Elapsed       time.Duration // time since typing started
+	ETA           time.Duration // estimated time until all characters are sent
+	KeysPerSecond float64       // effective key presses per second
+}
+
+// ProgressFunc is called with the progress of a Type call.
+// It is called while the KBWrap is locked and must not use it.
+type ProgressFunc func(p Progress)
+
+// ProgressChannel returns a ProgressFunc that sends the progress to the channel.
+// While the channel is full, the stale update in it is replaced by the latest one,
+// so that a slow receiver does not slow down typing and still gets the final state.
+// Replacing takes the stale update out of the channel, which is why the channel is
+// not send-only. The channel needs a buffer, as unbuffered channels only get updates
+// while the receiver is waiting.
+func ProgressChannel(ch chan Progress) ProgressFunc {
+	return func(p Progress) {
+		for {
+			select {
+			case ch <- p:
+				return
+			default:
+			}
+			select {
+			case <-ch:
+			default:
+				if cap(ch) == 0 {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Stats summarizes all keystrokes of a KBWrap.
+type Stats struct {
+	Keys   int // key down events sent
+	Chars  int // characters typed
+	Errors int // errors that occurred while sending key events
+
+	// time spent waiting in each phase of the keystrokes
+	Before time.Duration
+	Down   time.Duration
+	After  time.Duration
+}
+
+// Stats returns the statistics of all keystrokes since the KBWrap was created.
+// In dry run mode, the planned delays are summed up.
+func (kb *KBWrap) Stats() Stats {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+
+	return kb.stats
+}
+
+// delayPhase is the phase of a keystroke a delay belongs to.
+type delayPhase int
+
+const (
+	phaseBefore delayPhase = iota
+	phaseDown
+	phaseAfter
+)
+
+func (p delayPhase) String() string {
+	switch p {
+	case phaseDown:
+		return "down"
+	case phaseAfter:
+		return "after"
+	default:
+		return "before"
+	}
+}
+
+// spent adds the time spent waiting in a phase to the statistics.
+func (s *Stats) spent(phase delayPhase, d time.Duration) {
+	switch phase {
+	case phaseBefore:
+		s.Before += d
+	case phaseDown:
+		s.Down += d
+	case phaseAfter:
+		s.After += d
+	}
+}
+
+// TypeWithProgress types out a string like TypeContext and calls f before the first
+// and after every character.
+func (kb *KBWrap) TypeWithProgress(ctx context.Context, s string, f ProgressFunc) (n int, err error) {
+	return kb.typeContext(ctx, s, KeyCode{}, f)
+}
+
+// progressTracker computes the Progress of a Type call.
+type progressTracker struct {
+	kb    *KBWrap
+	f     ProgressFunc
+	total int
+	start time.Time
+	clock time.Duration // virtual time of the dry run at the start
+	keys  int           // key down events sent before the start
+}
+
+func (kb *KBWrap) newProgressTracker(f ProgressFunc, total int) *progressTracker {
+	if f == nil {
+		return nil
+	}
+	return &progressTracker{
+		kb:    kb,
+		f:     f,
+		total: total,
+		start: time.Now(),
+		clock: kb.clock,
+		keys:  kb.stats.Keys,
+	}
+}
+
+// report calls the ProgressFunc with the number of characters sent so far.
+func (t *progressTracker) report(sent int) {
+	if t == nil {
+		return
+	}
+	elapsed := time.Since(t.start)
+	if t.kb.dryRun != nil {
+		elapsed = t.kb.clock - t.clock
+	}
+	p := Progress{
+		Sent:    sent,
+		Total:   t.total,
+		Elapsed: elapsed,
+	}
+	if sent > 0 {
+		p.ETA = elapsed / time.Duration(sent) * time.Duration(t.total-sent)
+	}
+	if elapsed > 0 {
+		p.KeysPerSecond = float64(t.kb.stats.Keys-t.keys) / elapsed.Seconds()
+	}
+	t.f(p)
+}
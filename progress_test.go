@@ -0,0 +1,85 @@
+package sendkeys
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestTypeWithProgress(t *testing.T) {
+	k, err := NewKBWrapWithOptions(
+		DryRun(io.Discard, DryRunTable),
+		WithKeyMap(KeyMap{'a': SimpleKeyCode(30), 'b': SimpleKeyCode(48)}),
+		DelayBefore(10*time.Millisecond),
+		KeystrokeDuration(30*time.Millisecond),
+		DelayAfter(10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var progress []Progress
+	n, err := k.TypeWithProgress(context.Background(), "abab", func(p Progress) {
+		progress = append(progress, p)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 4 {
+		t.Errorf("expected 4 characters, got %d", n)
+	}
+	if len(progress) != 5 {
+		t.Fatalf("expected 5 updates, got %d", len(progress))
+	}
+	if p := progress[0]; p.Sent != 0 || p.Total != 4 || p.Elapsed != 0 {
+		t.Errorf("unexpected initial progress: %+v", p)
+	}
+	p := progress[1]
+	if p.Sent != 1 || p.Elapsed != 50*time.Millisecond || p.ETA != 150*time.Millisecond || p.KeysPerSecond != 20 {
+		t.Errorf("unexpected progress: %+v", p)
+	}
+	if p := progress[4]; p.Sent != 4 || p.ETA != 0 || p.Elapsed != 200*time.Millisecond {
+		t.Errorf("unexpected final progress: %+v", p)
+	}
+
+	stats := k.Stats()
+	expected := Stats{
+		Keys:   4,
+		Chars:  4,
+		Before: 40 * time.Millisecond,
+		Down:   120 * time.Millisecond,
+		After:  40 * time.Millisecond,
+	}
+	if stats != expected {
+		t.Errorf("expected %+v, got %+v", expected, stats)
+	}
+}
+
+func TestProgressChannel(t *testing.T) {
+	k, _ := newRecordingKBWrap(t, WithKeyMap(KeyMap{'a': SimpleKeyCode(30)}))
+
+	ch := make(chan Progress, 1)
+	_, err := k.TypeWithProgress(context.Background(), "aaa", ProgressChannel(ch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// stale updates are replaced while the channel is full
+	p := <-ch
+	if p.Sent != 3 || p.Total != 3 {
+		t.Errorf("unexpected progress: %+v", p)
+	}
+	if len(ch) != 0 {
+		t.Errorf("unexpected stale update: %+v", <-ch)
+	}
+}
+
+func TestStatsErrors(t *testing.T) {
+	k, _ := newFailingKBWrap(t, Stubborn)
+
+	_ = k.Type("abc")
+	stats := k.Stats()
+	if stats.Keys != 2 || stats.Chars != 3 || stats.Errors != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
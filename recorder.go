@@ -0,0 +1,172 @@
+package sendkeys
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrUnexpectedKeyEvent is returned by Recorder.Text in case the recorded
+	// events cannot be translated back into a string.
+	ErrUnexpectedKeyEvent = errors.New("unexpected key event")
+	// ErrBackendClosed is returned when key events are sent to a closed Backend.
+	ErrBackendClosed = errors.New("backend closed")
+)
+
+// Recorder is a Backend that does not send any key events
+// but records them in memory instead.
+// It is mostly useful for deterministic tests.
+type Recorder struct {
+	mu     sync.Mutex
+	events []KeyEvent
+	closed bool
+}
+
+// NewRecorder creates a new in-memory recording Backend.
+func NewRecorder() *Recorder {
+	return &Recorder{
+		events: []KeyEvent{},
+	}
+}
+
+func (r *Recorder) record(down bool, key KeyCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return ErrBackendClosed
+	}
+	r.events = append(r.events, KeyEvent{
+		Down: down,
+		Key:  key,
+		Time: time.Now(),
+	})
+	return nil
+}
+
+func (r *Recorder) Press(key KeyCode) error {
+	return r.record(true, key)
+}
+
+func (r *Recorder) Release(key KeyCode) error {
+	return r.record(false, key)
+}
+
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+	return nil
+}
+
+// Events returns a copy of all recorded events in the order they were sent.
+func (r *Recorder) Events() []KeyEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make([]KeyEvent, len(r.events))
+	copy(result, r.events)
+	return result
+}
+
+// Keys returns the keys of all recorded key down events.
+func (r *Recorder) Keys() []KeyCode {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make([]KeyCode, 0, len(r.events)/2)
+	for _, e := range r.events {
+		if e.Down {
+			result = append(result, e.Key)
+		}
+	}
+	return result
+}
+
+// Reset removes all recorded events.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = []KeyEvent{}
+}
+
+// Text reconstructs the typed string from the recorded events with the given KeyMap.
+// Every key down event must be followed by the key up event of the same key.
+func (r *Recorder) Text(keyMap KeyMap) (string, error) {
+	return r.TextWithSequences(keyMap, nil)
+}
+
+// TextWithSequences reconstructs the typed string from the recorded events
+// with the given KeyMap and KeySequences. The longest matching key sequence
+// takes precedence over the KeyMap.
+func (r *Recorder) TextWithSequences(keyMap KeyMap, sequences KeySequences) (string, error) {
+	var (
+		events  = r.Events()
+		keys    = make([]KeyCode, 0, len(events)/2)
+		reverse = keyMap.Reverse()
+		sb      strings.Builder
+	)
+
+	for i := 0; i < len(events); i += 2 {
+		down := events[i]
+		if !down.Down {
+			return "", fmt.Errorf("%w: event %d: expected key down, got: %s", ErrUnexpectedKeyEvent, i, down)
+		}
+		if i+1 >= len(events) {
+			return "", fmt.Errorf("%w: event %d: key was not released: %s", ErrUnexpectedKeyEvent, i, down)
+		}
+		up := events[i+1]
+		if up.Down || up.Key != down.Key {
+			return "", fmt.Errorf("%w: event %d: expected key up of %s, got: %s", ErrUnexpectedKeyEvent, i+1, down.Key, up)
+		}
+		keys = append(keys, down.Key)
+	}
+
+	for i := 0; i < len(keys); {
+		var (
+			char   rune
+			length = 0
+		)
+		for r, seq := range sequences {
+			if len(seq) == 0 || len(seq) < length || !hasKeyPrefix(keys[i:], seq) {
+				continue
+			}
+			if len(seq) == length && r > char {
+				// deterministic choice between identical sequences
+				continue
+			}
+			char, length = r, len(seq)
+		}
+		if length == 0 {
+			var ok bool
+			char, ok = reverse[keys[i]]
+			if !ok && keys[i].Code == 0 {
+				char, ok = keysymToRune(keys[i].Keysym)
+			}
+			if !ok {
+				return sb.String(), fmt.Errorf("%w: key %d: %s", ErrKeyMappingNotFound, i, keys[i])
+			}
+			length = 1
+		}
+		sb.WriteRune(char)
+		i += length
+	}
+	return sb.String(), nil
+}
+
+func hasKeyPrefix(keys, prefix []KeyCode) bool {
+	if len(prefix) > len(keys) {
+		return false
+	}
+	for i, key := range prefix {
+		if keys[i] != key {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,113 @@
+package sendkeys
+
+import (
+	"errors"
+	"sort"
+	"testing"
+)
+
+func newRecordingKBWrap(t *testing.T, opts ...KBOpt) (*KBWrap, *Recorder) {
+	t.Helper()
+	rec := NewRecorder()
+	opts = append([]KBOpt{
+		WithBackend(rec),
+		KeystrokeDuration(0),
+		DelayAfter(0),
+	}, opts...)
+	k, err := NewKBWrapWithOptions(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k, rec
+}
+
+func keyMapString(keyMap KeyMap) string {
+	runes := make([]rune, 0, len(keyMap))
+	for r := range keyMap {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool {
+		return runes[i] < runes[j]
+	})
+	return string(runes)
+}
+
+func TestRecorderKeyMapRoundTrip(t *testing.T) {
+	keyMaps := map[string]KeyMap{
+		"KeyMap_US_EN101":        KeyMap_US_EN101(),
+		"KeyMapLinuxQuartz":      KeyMapLinuxQuartz(),
+		"KeyMapDarwin_DE_QWERTZ": KeyMapDarwin_DE_QWERTZ(),
+	}
+
+	for name, keyMap := range keyMaps {
+		t.Run(name, func(t *testing.T) {
+			k, rec := newRecordingKBWrap(t, WithKeyMap(keyMap))
+			teststr := keyMapString(keyMap)
+
+			err := k.Type(teststr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			events := rec.Events()
+			if len(events) != 2*len([]rune(teststr)) {
+				t.Fatalf("expected %d events, got %d", 2*len([]rune(teststr)), len(events))
+			}
+
+			typed, err := rec.Text(k.KeyMap())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if typed != teststr {
+				t.Errorf("[FAIL] Have: %q, Wanted: %q", typed, teststr)
+			}
+		})
+	}
+}
+
+func TestRecorderEvents(t *testing.T) {
+	k, rec := newRecordingKBWrap(t, WithKeyMap(KeyMapLinuxQuartz()))
+
+	err := k.Type("aB")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []KeyEvent{
+		{Down: true, Key: SimpleKeyCode(30)},
+		{Down: false, Key: SimpleKeyCode(30)},
+		{Down: true, Key: ShiftKeyCode(48)},
+		{Down: false, Key: ShiftKeyCode(48)},
+	}
+	events := rec.Events()
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %d", len(expected), len(events))
+	}
+	for i, e := range events {
+		if e.Down != expected[i].Down || e.Key != expected[i].Key {
+			t.Errorf("event %d: expected %s, got %s", i, expected[i], e)
+		}
+		if i > 0 && e.Time.Before(events[i-1].Time) {
+			t.Errorf("event %d: timestamp is before the previous event", i)
+		}
+	}
+
+	rec.Reset()
+	if len(rec.Events()) != 0 {
+		t.Fatal("expected no events after reset")
+	}
+
+	k.TypeRaw(SimpleKeyCode(-1))
+	_, err = rec.Text(k.KeyMap())
+	if !errors.Is(err, ErrKeyMappingNotFound) {
+		t.Fatalf("expected %v, got %v", ErrKeyMappingNotFound, err)
+	}
+
+	err = k.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Press(SimpleKeyCode(1)) == nil {
+		t.Fatal("expected error after backend was closed")
+	}
+}
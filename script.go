@@ -0,0 +1,355 @@
+package sendkeys
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrInvalidScript is returned when a script cannot be parsed.
+var ErrInvalidScript = errors.New("invalid script")
+
+// A Script is a parsed macro script. Scripts consist of text, which is typed as is,
+// and commands in curly braces. Command names are case insensitive.
+//
+//	{ENTER}               press a named key, see ParseKey
+//	{TAB 3}               press a named key three times
+//	{CTRL+C}              press a chord, see ParseChord
+//	{CTRL+V 2}            press a chord twice
+//	{SLEEP 500ms}         wait, plain numbers are milliseconds
+//	{HOLD SHIFT}...{RELEASE}
+//	                      press modifiers or keys and hold them until RELEASE
+//	{REPEAT 3}...{END}    repeat the enclosed script
+//	{SET name value}      set a variable to the rest of the command
+//	{$name}               type the value of a variable
+//	{{ and }}             type a literal { or }
+//
+// Arguments of commands can refer to variables as well, e.g. {SLEEP $delay}.
+type Script struct {
+	Nodes []ScriptNode
+}
+
+// ScriptPos is the position of a node in the script source.
+// Line and column start at 1, the column is counted in characters.
+type ScriptPos struct {
+	Line int
+	Col  int
+}
+
+func (p ScriptPos) String() string {
+	return strconv.Itoa(p.Line) + ":" + strconv.Itoa(p.Col)
+}
+
+// Pos returns the position itself, so that ScriptPos can be embedded into nodes.
+func (p ScriptPos) Pos() ScriptPos {
+	return p
+}
+
+// ScriptError is an error at a specific position of a script.
+type ScriptError struct {
+	ScriptPos
+	Err error
+}
+
+func (e *ScriptError) Error() string {
+	return e.ScriptPos.String() + ": " + e.Err.Error()
+}
+
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
+
+// ScriptNode is a node of the abstract syntax tree of a Script.
+type ScriptNode interface {
+	Pos() ScriptPos
+}
+
+// TextNode types text.
+type TextNode struct {
+	ScriptPos
+	Text string
+}
+
+// KeyNode presses a named key or a chord Count times.
+type KeyNode struct {
+	ScriptPos
+	Chord string
+	Count string // empty for 1
+}
+
+// SleepNode waits for the given duration.
+type SleepNode struct {
+	ScriptPos
+	Duration string
+}
+
+// SetNode sets a variable.
+type SetNode struct {
+	ScriptPos
+	Name  string
+	Value string
+}
+
+// VarNode types the value of a variable.
+type VarNode struct {
+	ScriptPos
+	Name string
+}
+
+// HoldNode holds modifiers or keys while its body is executed.
+type HoldNode struct {
+	ScriptPos
+	Chord string
+	Body  []ScriptNode
+}
+
+// RepeatNode executes its body Count times.
+type RepeatNode struct {
+	ScriptPos
+	Count string
+	Body  []ScriptNode
+}
+
+// ParseScript parses the source of a macro script.
+// Errors are of type *ScriptError and wrap ErrInvalidScript.
+func ParseScript(src string) (*Script, error) {
+	p := &scriptParser{src: src, pos: ScriptPos{Line: 1, Col: 1}}
+	nodes, end, err := p.parseBlock()
+	if err != nil {
+		return nil, err
+	}
+	if end != nil {
+		return nil, scriptErrorf(end.pos, "unexpected {%s}", end.name)
+	}
+	return &Script{Nodes: nodes}, nil
+}
+
+func scriptErrorf(pos ScriptPos, format string, args ...any) error {
+	return &ScriptError{
+		ScriptPos: pos,
+		Err:       fmt.Errorf("%w: %s", ErrInvalidScript, fmt.Sprintf(format, args...)),
+	}
+}
+
+type scriptParser struct {
+	src string
+	i   int
+	pos ScriptPos
+}
+
+// scriptCommand is a command in curly braces.
+type scriptCommand struct {
+	pos  ScriptPos
+	raw  string // name as written
+	name string // upper case name
+	args string // trimmed rest of the command
+}
+
+// next returns the next rune and advances the position.
+func (p *scriptParser) next() rune {
+	r, size := utf8.DecodeRuneInString(p.src[p.i:])
+	p.i += size
+	if r == '\n' {
+		p.pos.Line++
+		p.pos.Col = 1
+	} else {
+		p.pos.Col++
+	}
+	return r
+}
+
+// parseBlock parses nodes until the end of the script or until an END or RELEASE command,
+// which is returned.
+func (p *scriptParser) parseBlock() (nodes []ScriptNode, end *scriptCommand, err error) {
+	var (
+		text    strings.Builder
+		textPos ScriptPos
+	)
+	flush := func() {
+		if text.Len() > 0 {
+			nodes = append(nodes, &TextNode{ScriptPos: textPos, Text: text.String()})
+			text.Reset()
+		}
+	}
+	addText := func(pos ScriptPos, r rune) {
+		if text.Len() == 0 {
+			textPos = pos
+		}
+		text.WriteRune(r)
+	}
+
+	for p.i < len(p.src) {
+		pos := p.pos
+		r := p.next()
+		switch {
+		case r == '{' && strings.HasPrefix(p.src[p.i:], "{"):
+			p.next()
+			addText(pos, '{')
+		case r == '}' && strings.HasPrefix(p.src[p.i:], "}"):
+			p.next()
+			addText(pos, '}')
+		case r == '}':
+			return nil, nil, scriptErrorf(pos, "unexpected }, use }} for a literal }")
+		case r == '{':
+			flush()
+			cmd, err := p.parseCommand(pos)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch cmd.name {
+			case "END", "RELEASE":
+				return nodes, cmd, nil
+			}
+			node, err := p.parseNode(cmd)
+			if err != nil {
+				return nil, nil, err
+			}
+			nodes = append(nodes, node)
+		default:
+			addText(pos, r)
+		}
+	}
+	flush()
+	return nodes, nil, nil
+}
+
+// parseCommand parses the command after its opening brace.
+func (p *scriptParser) parseCommand(pos ScriptPos) (*scriptCommand, error) {
+	start := p.i
+	for {
+		if p.i >= len(p.src) {
+			return nil, scriptErrorf(pos, "unterminated command")
+		}
+		switch p.next() {
+		case '}':
+			content := strings.TrimSpace(p.src[start : p.i-1])
+			if content == "" {
+				return nil, scriptErrorf(pos, "empty command")
+			}
+			name, args, _ := strings.Cut(content, " ")
+			return &scriptCommand{
+				pos:  pos,
+				raw:  name,
+				name: strings.ToUpper(name),
+				args: strings.TrimSpace(args),
+			}, nil
+		case '{', '\n':
+			return nil, scriptErrorf(pos, "unterminated command")
+		}
+	}
+}
+
+func (p *scriptParser) parseNode(cmd *scriptCommand) (ScriptNode, error) {
+	switch {
+	case cmd.name == "SLEEP":
+		if cmd.args == "" {
+			return nil, scriptErrorf(cmd.pos, "SLEEP needs a duration")
+		}
+		if !isScriptVar(cmd.args) {
+			if _, err := parseScriptDuration(cmd.args); err != nil {
+				return nil, scriptErrorf(cmd.pos, "%v", err)
+			}
+		}
+		return &SleepNode{ScriptPos: cmd.pos, Duration: cmd.args}, nil
+	case cmd.name == "SET":
+		name, value, _ := strings.Cut(cmd.args, " ")
+		if !isScriptVarName(name) {
+			return nil, scriptErrorf(cmd.pos, "invalid variable name: %q", name)
+		}
+		return &SetNode{ScriptPos: cmd.pos, Name: name, Value: strings.TrimSpace(value)}, nil
+	case strings.HasPrefix(cmd.name, "$"):
+		if cmd.args != "" || !isScriptVar(cmd.raw) {
+			return nil, scriptErrorf(cmd.pos, "invalid variable: %q", cmd.raw)
+		}
+		return &VarNode{ScriptPos: cmd.pos, Name: cmd.raw[1:]}, nil
+	case cmd.name == "REPEAT":
+		if err := checkScriptCount(cmd.pos, cmd.args); err != nil {
+			return nil, err
+		}
+		body, end, err := p.parseBlock()
+		if err != nil {
+			return nil, err
+		}
+		if end == nil || end.name != "END" {
+			return nil, scriptErrorf(cmd.pos, "REPEAT without END")
+		}
+		return &RepeatNode{ScriptPos: cmd.pos, Count: cmd.args, Body: body}, nil
+	case cmd.name == "HOLD":
+		if cmd.args == "" {
+			return nil, scriptErrorf(cmd.pos, "HOLD needs keys")
+		}
+		body, end, err := p.parseBlock()
+		if err != nil {
+			return nil, err
+		}
+		if end == nil || end.name != "RELEASE" {
+			return nil, scriptErrorf(cmd.pos, "HOLD without RELEASE")
+		}
+		return &HoldNode{ScriptPos: cmd.pos, Chord: cmd.args, Body: body}, nil
+	default:
+		if err := checkScriptCount(cmd.pos, cmd.args); err != nil {
+			return nil, err
+		}
+		return &KeyNode{ScriptPos: cmd.pos, Chord: cmd.raw, Count: cmd.args}, nil
+	}
+}
+
+// isScriptVar reports whether the argument refers to a variable, e.g. $delay.
+func isScriptVar(arg string) bool {
+	return strings.HasPrefix(arg, "$") && isScriptVarName(arg[1:])
+}
+
+func isScriptVarName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// parseScriptDuration parses a duration like 1.5s; plain numbers are milliseconds.
+func parseScriptDuration(s string) (time.Duration, error) {
+	if ms, err := strconv.ParseUint(s, 10, 32); err == nil {
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("negative duration: %s", s)
+	}
+	return d, nil
+}
+
+// parseScriptCount parses a repetition count, which defaults to 1.
+func parseScriptCount(s string) (int, error) {
+	if s == "" {
+		return 1, nil
+	}
+	n, err := strconv.ParseUint(s, 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid count: %q", s)
+	}
+	return int(n), nil
+}
+
+func checkScriptCount(pos ScriptPos, s string) error {
+	if isScriptVar(s) {
+		return nil
+	}
+	if _, err := parseScriptCount(s); err != nil {
+		return scriptErrorf(pos, "%v", err)
+	}
+	return nil
+}
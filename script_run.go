@@ -0,0 +1,186 @@
+package sendkeys
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RunScript parses and runs a macro script, see Script for the syntax.
+func (kb *KBWrap) RunScript(ctx context.Context, src string) error {
+	s, err := ParseScript(src)
+	if err != nil {
+		return err
+	}
+	return s.Run(ctx, kb, nil)
+}
+
+// Run executes the script with the given keyboard wrapper until it is finished
+// or the context is cancelled. vars contains the initial variables and may be nil.
+// Errors are of type *ScriptError and point at the failing command.
+// Keys that are held by the script are always released.
+func (s *Script) Run(ctx context.Context, kb *KBWrap, vars map[string]string) error {
+	r := &scriptRunner{
+		kb:   kb,
+		vars: make(map[string]string, len(vars)),
+	}
+	for k, v := range vars {
+		r.vars[k] = v
+	}
+	return r.run(ctx, s.Nodes)
+}
+
+type scriptRunner struct {
+	kb   *KBWrap
+	vars map[string]string
+}
+
+func (r *scriptRunner) run(ctx context.Context, nodes []ScriptNode) error {
+	for _, node := range nodes {
+		err := ctx.Err()
+		if err == nil {
+			err = r.exec(ctx, node)
+		}
+		if err != nil {
+			if _, ok := err.(*ScriptError); ok {
+				return err
+			}
+			return &ScriptError{ScriptPos: node.Pos(), Err: err}
+		}
+	}
+	return nil
+}
+
+func (r *scriptRunner) exec(ctx context.Context, node ScriptNode) error {
+	switch n := node.(type) {
+	case *TextNode:
+		_, err := r.kb.typeContext(ctx, n.Text, KeyCode{}, nil)
+		return err
+	case *VarNode:
+		value, err := r.lookup("$" + n.Name)
+		if err != nil {
+			return err
+		}
+		_, err = r.kb.typeContext(ctx, value, KeyCode{}, nil)
+		return err
+	case *SetNode:
+		value, err := r.lookup(n.Value)
+		if err != nil {
+			return err
+		}
+		r.vars[n.Name] = value
+		return nil
+	case *SleepNode:
+		arg, err := r.lookup(n.Duration)
+		if err != nil {
+			return err
+		}
+		d, err := parseScriptDuration(arg)
+		if err != nil {
+			return err
+		}
+		return r.sleep(ctx, d)
+	case *KeyNode:
+		count, err := r.count(n.Count)
+		if err != nil {
+			return err
+		}
+		c, err := parseChord(n.Chord, r.kb.keyMap, r.kb.namedKey)
+		if err != nil {
+			return err
+		}
+		return r.press(ctx, c, count)
+	case *RepeatNode:
+		count, err := r.count(n.Count)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < count; i++ {
+			err = r.run(ctx, n.Body)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case *HoldNode:
+		return r.hold(ctx, n)
+	default:
+		return fmt.Errorf("unknown script node: %T", node)
+	}
+}
+
+// lookup resolves an argument that refers to a variable.
+func (r *scriptRunner) lookup(arg string) (string, error) {
+	if !isScriptVar(arg) {
+		return arg, nil
+	}
+	value, ok := r.vars[arg[1:]]
+	if !ok {
+		return "", fmt.Errorf("undefined variable: %s", arg)
+	}
+	return value, nil
+}
+
+func (r *scriptRunner) count(arg string) (int, error) {
+	arg, err := r.lookup(arg)
+	if err != nil {
+		return 0, err
+	}
+	return parseScriptCount(strings.TrimSpace(arg))
+}
+
+func (r *scriptRunner) press(ctx context.Context, c Chord, count int) error {
+	r.kb.mu.Lock()
+	defer r.kb.mu.Unlock()
+
+	for i := 0; i < count; i++ {
+		_, err := r.kb.send(ctx, c.Events())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sleep waits like the delays of the keyboard wrapper. The wrapper is only locked
+// in dry run mode, where the sleep advances its clock, so that a long sleep does not
+// block other calls, e.g. ReleaseAll.
+func (r *scriptRunner) sleep(ctx context.Context, d time.Duration) error {
+	if r.kb.dryRun != nil {
+		r.kb.mu.Lock()
+		defer r.kb.mu.Unlock()
+	}
+	return r.kb.sleep(ctx, d)
+}
+
+// hold holds keys down while the body is executed. Modifiers without a key,
+// e.g. {HOLD SHIFT}, are pressed as keys of their own, see KeyCode.ModifiersOnly.
+func (r *scriptRunner) hold(ctx context.Context, n *HoldNode) (err error) {
+	c := Chord{}
+	if mods, ok := parseChordModifiers(n.Chord); ok {
+		c = append(c, mods)
+	} else {
+		c, err = parseChord(n.Chord, r.kb.keyMap, r.kb.namedKey)
+		if err != nil {
+			return err
+		}
+	}
+	for i, key := range c {
+		err = r.kb.KeyDown(key)
+		if err != nil {
+			for j := i - 1; j >= 0; j-- {
+				_ = r.kb.KeyUp(c[j])
+			}
+			return err
+		}
+	}
+	defer func() {
+		for i := len(c) - 1; i >= 0; i-- {
+			if upErr := r.kb.KeyUp(c[i]); upErr != nil && err == nil {
+				err = upErr
+			}
+		}
+	}()
+	return r.run(ctx, n.Body)
+}
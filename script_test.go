@@ -0,0 +1,201 @@
+package sendkeys
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseScript(t *testing.T) {
+	s, err := ParseScript("hi{{x}}\n{TAB 3}{REPEAT $n}{HOLD shift}a{RELEASE}{END}{SLEEP 5}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []ScriptNode{
+		&TextNode{ScriptPos: ScriptPos{1, 1}, Text: "hi{x}\n"},
+		&KeyNode{ScriptPos: ScriptPos{2, 1}, Chord: "TAB", Count: "3"},
+		&RepeatNode{ScriptPos: ScriptPos{2, 8}, Count: "$n", Body: []ScriptNode{
+			&HoldNode{ScriptPos: ScriptPos{2, 19}, Chord: "shift", Body: []ScriptNode{
+				&TextNode{ScriptPos: ScriptPos{2, 31}, Text: "a"},
+			}},
+		}},
+		&SleepNode{ScriptPos: ScriptPos{2, 46}, Duration: "5"},
+	}
+	if !reflect.DeepEqual(s.Nodes, expected) {
+		t.Fatalf("unexpected nodes: %#v", s.Nodes)
+	}
+}
+
+func TestParseScriptErrors(t *testing.T) {
+	tests := map[string]ScriptPos{
+		"abc}":                        {1, 4},
+		"a\n {ENTER":                  {2, 2},
+		"{}":                          {1, 1},
+		"{REPEAT 2}a":                 {1, 1},
+		"x\n{HOLD CTRL}a{END}":        {2, 1},
+		"{RELEASE}":                   {1, 1},
+		"{SLEEP soon}":                {1, 1},
+		"{TAB many}":                  {1, 1},
+		"{SET 1x y}":                  {1, 1},
+		"ä{REPEAT 1}{REPEAT 1}ö{END}": {1, 2},
+	}
+	for src, pos := range tests {
+		_, err := ParseScript(src)
+		var serr *ScriptError
+		if !errors.As(err, &serr) || !errors.Is(err, ErrInvalidScript) {
+			t.Errorf("%q: expected script error, got %v", src, err)
+			continue
+		}
+		if serr.ScriptPos != pos {
+			t.Errorf("%q: expected error at %s, got %v", src, pos, err)
+		}
+	}
+}
+
+func TestRunScript(t *testing.T) {
+	keyMap := KeyMapLinuxQuartz()
+	k, rec := newRecordingKBWrap(t, WithKeyMap(keyMap))
+
+	err := k.RunScript(context.Background(), "a{ENTER}{TAB 2}{CTRL+C}{HOLD SHIFT}b{RELEASE}{SET x c}{$x}{REPEAT 2}d{END}{SLEEP 1ms}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tab := SimpleKeyCode(platformKeys[KeyTab])
+	expected := []KeyCode{
+		keyMap['a'],
+		SimpleKeyCode(platformKeys[KeyEnter]),
+		tab, tab,
+		withModifiers(keyMap['c'], KeyCode{ModifierCTRL: true}),
+		{ModifierSHIFT: true}, keyMap['b'],
+		keyMap['c'],
+		keyMap['d'], keyMap['d'],
+	}
+	if keys := rec.Keys(); !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("expected %v, got %v", expected, keys)
+	}
+}
+
+func TestRunScriptHoldKey(t *testing.T) {
+	keyMap := KeyMapLinuxQuartz()
+	k, rec := newRecordingKBWrap(t, WithKeyMap(keyMap))
+
+	s, err := ParseScript("{HOLD a}{SLEEP $d}{RELEASE}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Run(context.Background(), k, map[string]string{"d": "10ms"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := rec.Events()
+	if len(events) != 2 || !events[0].Down || events[1].Down {
+		t.Fatalf("unexpected events: %v", events)
+	}
+	if d := events[1].Time.Sub(events[0].Time); d < 10*time.Millisecond {
+		t.Fatalf("key was held for %s only", d)
+	}
+}
+
+func TestRunScriptHoldModifiers(t *testing.T) {
+	keyMap := KeyMapLinuxQuartz()
+	k, rec := newRecordingKBWrap(t, WithKeyMap(keyMap))
+
+	err := k.RunScript(context.Background(), "{HOLD SHIFT}A b{RELEASE}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the held shift key is not released together with the upper case character
+	shift := KeyCode{ModifierSHIFT: true}
+	expected := []KeyEvent{KeyDownEvent(shift)}
+	for _, r := range "a b" {
+		expected = append(expected, KeyDownEvent(keyMap[r]), KeyUpEvent(keyMap[r]))
+	}
+	expected = append(expected, KeyUpEvent(shift))
+
+	events := rec.Events()
+	if len(events) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, events)
+	}
+	for i, e := range events {
+		if e.Down != expected[i].Down || e.Key != expected[i].Key {
+			t.Fatalf("expected %v, got %v", expected, events)
+		}
+	}
+}
+
+func TestRunScriptErrors(t *testing.T) {
+	tests := map[string]ScriptPos{
+		"ab\n{$missing}":               {2, 1},
+		"{HOLD ctrl}\n{NOPE}{RELEASE}": {2, 1},
+		"{REPEAT $n}x{END}":            {1, 1},
+	}
+	for src, pos := range tests {
+		k, _ := newRecordingKBWrap(t, WithKeyMap(KeyMapLinuxQuartz()))
+		err := k.RunScript(context.Background(), src)
+		var serr *ScriptError
+		if !errors.As(err, &serr) {
+			t.Errorf("%q: expected script error, got %v", src, err)
+			continue
+		}
+		if serr.ScriptPos != pos {
+			t.Errorf("%q: expected error at %s, got %v", src, pos, err)
+		}
+		if len(k.Held()) != 0 {
+			t.Errorf("%q: keys are still held: %v", src, k.Held())
+		}
+	}
+}
+
+func TestRunScriptDryRunConcurrent(t *testing.T) {
+	k, err := NewKBWrapWithOptions(DryRun(io.Discard, DryRunTable), WithKeyMap(KeyMapLinuxQuartz()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- k.RunScript(context.Background(), "{REPEAT 20}{SLEEP 1s}{END}")
+	}()
+	for i := 0; i < 20; i++ {
+		err = k.Type("a")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunScriptSleepUnlocked(t *testing.T) {
+	k, _ := newRecordingKBWrap(t, WithKeyMap(KeyMapLinuxQuartz()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- k.RunScript(ctx, "{HOLD a}{SLEEP 10s}{RELEASE}")
+	}()
+	for len(k.Held()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// other calls are not blocked by the sleep
+	start := time.Now()
+	err := k.ReleaseAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("ReleaseAll was blocked for %s", d)
+	}
+	cancel()
+	<-done
+}
@@ -0,0 +1,351 @@
+package sendkeys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// ErrInvalidSendNotation is returned when the Send notation is malformed,
+	// e.g. because of an unterminated brace or an unbalanced parenthesis.
+	ErrInvalidSendNotation = errors.New("invalid send notation")
+	// ErrUnsupportedToken is returned for tokens of the Send notation that are
+	// valid in AutoHotkey or SendKeys but cannot be sent, e.g. {Click}.
+	ErrUnsupportedToken = errors.New("unsupported token")
+)
+
+// SendError is an error at a specific token of the Send notation.
+type SendError struct {
+	Offset int    // byte offset of the token
+	Token  string // the token as written
+	Err    error
+}
+
+func (e *SendError) Error() string {
+	return fmt.Sprintf("offset %d: %q: %v", e.Offset, e.Token, e.Err)
+}
+
+func (e *SendError) Unwrap() error {
+	return e.Err
+}
+
+// sendKeyNames are the AutoHotkey and SendKeys names of keys that differ from ParseKey.
+var sendKeyNames = map[string]Key{
+	"bs":               KeyBackSpace,
+	"backspace":        KeyBackSpace,
+	"appskey":          KeyMenu,
+	"help":             KeyInsert,
+	"add":              KeyNumpadAdd,
+	"subtract":         KeyNumpadSubtract,
+	"multiply":         KeyNumpadMultiply,
+	"divide":           KeyNumpadDivide,
+	"numpadsub":        KeyNumpadSubtract,
+	"numpadmult":       KeyNumpadMultiply,
+	"numpaddiv":        KeyNumpadDivide,
+	"numpaddot":        KeyNumpadDecimal,
+	"volume_mute":      KeyVolumeMute,
+	"volume_down":      KeyVolumeDown,
+	"volume_up":        KeyVolumeUp,
+	"media_play_pause": KeyMediaPlayPause,
+	"media_stop":       KeyMediaStop,
+	"media_next":       KeyMediaNext,
+	"media_prev":       KeyMediaPrevious,
+}
+
+// sendModifiers maps the prefix characters of the Send notation to modifiers.
+var sendModifiers = map[byte]Modifier{
+	'+': ModShift,
+	'^': ModCtrl,
+	'%': ModAlt, // SendKeys
+	'!': ModAlt, // AutoHotkey
+	'#': ModSuper,
+}
+
+// Send sends keys written in the AutoHotkey Send or Windows SendKeys notation:
+//
+//	^c          Ctrl+C, + is Shift, ! or % is Alt and # is Win/Cmd
+//	+(abc)      hold Shift while typing abc
+//	{ENTER 2}   press a named key twice, ~ is Enter as well
+//	!{F4}       named keys can be combined with modifiers
+//	{+}         a literal special character
+//	{U+1F600}   a character by its code point
+//	{a down}    press a key or modifier until {a up}
+//
+// The whole notation is parsed before any key is sent.
+// Errors are of type *SendError.
+func (kb *KBWrap) Send(notation string) error {
+	return kb.SendContext(context.Background(), notation)
+}
+
+// SendContext sends keys written in the AutoHotkey or SendKeys notation
+// unless the context is cancelled.
+func (kb *KBWrap) SendContext(ctx context.Context, notation string) error {
+	keys, err := kb.parseSendNotation(notation)
+	if err != nil {
+		return err
+	}
+
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+
+	for _, events := range keys {
+		_, err := kb.send(ctx, events)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type sendParser struct {
+	kb       *KBWrap
+	s        string
+	i        int
+	keys     [][]KeyEvent
+	next     KeyCode   // modifiers of the next key
+	groups   []KeyCode // modifiers of the enclosing parentheses
+	groupPos []int
+}
+
+func (kb *KBWrap) parseSendNotation(s string) ([][]KeyEvent, error) {
+	p := &sendParser{kb: kb, s: s}
+	for p.i < len(p.s) {
+		err := p.parseToken()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if p.next != (KeyCode{}) {
+		return nil, &SendError{Offset: len(s), Token: "", Err: fmt.Errorf("%w: modifier without key", ErrInvalidSendNotation)}
+	}
+	if len(p.groups) > 0 {
+		offset := p.groupPos[len(p.groupPos)-1]
+		return nil, &SendError{Offset: offset, Token: "(", Err: fmt.Errorf("%w: unbalanced parenthesis", ErrInvalidSendNotation)}
+	}
+	return p.keys, nil
+}
+
+// modifiers returns all modifiers that apply to the next key.
+func (p *sendParser) modifiers() KeyCode {
+	mods := p.next
+	for _, g := range p.groups {
+		mods = withModifiers(mods, g)
+	}
+	return mods
+}
+
+func (p *sendParser) add(events []KeyEvent) {
+	mods := p.modifiers()
+	if mods != (KeyCode{}) {
+		for i := range events {
+			events[i].Key = withModifiers(events[i].Key, mods)
+		}
+	}
+	p.keys = append(p.keys, events)
+	p.next = KeyCode{}
+}
+
+func (p *sendParser) parseToken() error {
+	start := p.i
+	c := p.s[p.i]
+	if m, ok := sendModifiers[c]; ok {
+		p.i++
+		m.apply(&p.next)
+		return nil
+	}
+
+	switch c {
+	case '(':
+		p.i++
+		p.groups = append(p.groups, p.next)
+		p.groupPos = append(p.groupPos, start)
+		p.next = KeyCode{}
+		return nil
+	case ')':
+		p.i++
+		if len(p.groups) == 0 || p.next != (KeyCode{}) {
+			return &SendError{Offset: start, Token: ")", Err: fmt.Errorf("%w: unbalanced parenthesis", ErrInvalidSendNotation)}
+		}
+		p.groups = p.groups[:len(p.groups)-1]
+		p.groupPos = p.groupPos[:len(p.groupPos)-1]
+		return nil
+	case '~':
+		p.i++
+		return p.key(start, "~", KeyEnter, 1)
+	case '{':
+		return p.parseBraces()
+	}
+
+	r, size := utf8.DecodeRuneInString(p.s[p.i:])
+	p.i += size
+	return p.rune(start, p.s[start:p.i], r, 1)
+}
+
+// parseBraces parses a token like {ENTER}, {a 3}, {}} or {Shift down}.
+func (p *sendParser) parseBraces() error {
+	start := p.i
+	if strings.HasPrefix(p.s[start:], "{}") && !strings.HasPrefix(p.s[start:], "{}}") {
+		return &SendError{Offset: start, Token: "{}", Err: fmt.Errorf("%w: empty braces", ErrInvalidSendNotation)}
+	}
+	// the first character may be a closing brace, e.g. {}}
+	end := -1
+	if start+2 <= len(p.s) {
+		end = strings.IndexByte(p.s[start+2:], '}')
+	}
+	if end < 0 {
+		return &SendError{Offset: start, Token: p.s[start:], Err: fmt.Errorf("%w: unterminated brace", ErrInvalidSendNotation)}
+	}
+	p.i = start + 2 + end + 1
+	token := p.s[start:p.i]
+	content := p.s[start+1 : p.i-1]
+
+	name, arg := content, ""
+	if i := strings.LastIndexByte(content, ' '); i > 0 {
+		name, arg = content[:i], strings.TrimSpace(content[i+1:])
+	}
+
+	count := 1
+	switch strings.ToLower(arg) {
+	case "":
+	case "down":
+		return p.downUp(start, token, name, true)
+	case "up":
+		return p.downUp(start, token, name, false)
+	default:
+		n, err := strconv.ParseUint(arg, 10, 16)
+		if err != nil {
+			return &SendError{Offset: start, Token: token, Err: fmt.Errorf("%w: %q", ErrUnsupportedToken, arg)}
+		}
+		count = int(n)
+	}
+
+	if r, size := utf8.DecodeRuneInString(name); size == len(name) {
+		return p.rune(start, token, r, count)
+	}
+	if r, ok := parseSendCodePoint(name); ok {
+		return p.rune(start, token, r, count)
+	}
+	key, err := parseSendKey(name)
+	if err != nil {
+		return &SendError{Offset: start, Token: token, Err: err}
+	}
+	return p.key(start, token, key, count)
+}
+
+// downUp handles {name down} and {name up}. Modifiers are pressed as keys
+// of their own, see KeyCode.ModifiersOnly.
+func (p *sendParser) downUp(offset int, token, name string, down bool) error {
+	var (
+		events []KeyEvent
+		err    error
+	)
+	if m, ok := chordModifiers[strings.Replace(strings.ToLower(name), "control", "ctrl", 1)]; ok {
+		var mods KeyCode
+		if m.side != SideAny {
+			mods.ModifierSide = m.side
+		}
+		m.mod.apply(&mods)
+		events = Tap(mods)
+	} else if r, size := utf8.DecodeRuneInString(name); size == len(name) {
+		events, err = p.runeToKeys(offset, r)
+	} else {
+		var key Key
+		key, err = parseSendKey(name)
+		if err == nil {
+			var code KeyCode
+			code, err = p.kb.namedKey(key)
+			events = Tap(code)
+		}
+	}
+	if err != nil {
+		return &SendError{Offset: offset, Token: token, Err: err}
+	}
+	if events == nil {
+		return nil
+	}
+	if len(events) != 2 {
+		return &SendError{Offset: offset, Token: token, Err: fmt.Errorf("%w: key sequence cannot be held", ErrUnsupportedToken)}
+	}
+	if down {
+		events = events[:1]
+	} else {
+		events = events[1:]
+	}
+	p.add(events)
+	return nil
+}
+
+func (p *sendParser) rune(offset int, token string, r rune, count int) error {
+	for i := 0; i < count; i++ {
+		events, err := p.runeToKeys(offset, r)
+		if err != nil {
+			return &SendError{Offset: offset, Token: token, Err: err}
+		}
+		if events == nil {
+			continue
+		}
+		p.add(events)
+	}
+	if count == 0 {
+		p.next = KeyCode{}
+	}
+	return nil
+}
+
+// runeToKeys translates a character according to the UnmappedPolicy.
+// Errors are of type *UnmappedError.
+func (p *sendParser) runeToKeys(offset int, r rune) ([]KeyEvent, error) {
+	keys, _, errs := p.kb.resolve(string(r))
+	if len(errs) > 0 {
+		u := errs[0].(*UnmappedError)
+		u.UnmappedRune = textPosition(p.s, offset, r)
+		return nil, u
+	}
+	if len(keys) == 0 {
+		// skipped
+		return nil, nil
+	}
+	return keys[0], nil
+}
+
+func (p *sendParser) key(offset int, token string, key Key, count int) error {
+	code, err := p.kb.namedKey(key)
+	if err != nil {
+		return &SendError{Offset: offset, Token: token, Err: err}
+	}
+	for i := 0; i < count; i++ {
+		p.add(Tap(code))
+	}
+	if count == 0 {
+		p.next = KeyCode{}
+	}
+	return nil
+}
+
+// parseSendKey resolves the name of a key in braces.
+func parseSendKey(name string) (Key, error) {
+	if key, ok := sendKeyNames[strings.ToLower(name)]; ok {
+		return key, nil
+	}
+	key, err := ParseKey(name)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrUnsupportedToken, err)
+	}
+	return key, nil
+}
+
+// parseSendCodePoint parses U+XXXX code points.
+func parseSendCodePoint(name string) (rune, bool) {
+	if len(name) < 3 || !strings.EqualFold(name[:2], "u+") {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(name[2:], 16, 32)
+	if err != nil || n > utf8.MaxRune {
+		return 0, false
+	}
+	return rune(n), true
+}
@@ -0,0 +1,140 @@
+package sendkeys
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSend(t *testing.T) {
+	keyMap := KeyMapLinuxQuartz()
+	enter := SimpleKeyCode(platformKeys[KeyEnter])
+	ctrl := KeyCode{ModifierCTRL: true}
+	shift := KeyCode{ModifierSHIFT: true}
+	alt := KeyCode{ModifierALT: true}
+
+	tests := map[string][]KeyCode{
+		"^c":             {withModifiers(keyMap['c'], ctrl)},
+		"+{TAB}":         {withModifiers(SimpleKeyCode(platformKeys[KeyTab]), shift)},
+		"{ENTER 2}~":     {enter, enter, enter},
+		"!{F4}":          {withModifiers(SimpleKeyCode(platformKeys[KeyF4]), alt)},
+		"%{F4}":          {withModifiers(SimpleKeyCode(platformKeys[KeyF4]), alt)},
+		"+(ab)c":         {keyMap['A'], keyMap['B'], keyMap['c']},
+		"{+}{{}{}}{a 2}": {keyMap['+'], keyMap['{'], keyMap['}'], keyMap['a'], keyMap['a']},
+		"{U+0041}":       {keyMap['A']},
+		"{Shift down}ab{Shift up}c": {
+			shift, keyMap['a'], keyMap['b'], keyMap['c'],
+		},
+		"{LCtrl down}x{LCtrl up}": {
+			{ModifierCTRL: true, ModifierSide: SideLeft}, keyMap['x'],
+		},
+	}
+	for notation, expected := range tests {
+		t.Run(notation, func(t *testing.T) {
+			k, rec := newRecordingKBWrap(t, WithKeyMap(keyMap))
+			err := k.Send(notation)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if keys := rec.Keys(); !reflect.DeepEqual(keys, expected) {
+				t.Fatalf("expected %v, got %v", expected, keys)
+			}
+		})
+	}
+}
+
+func TestSendHold(t *testing.T) {
+	keyMap := KeyMapLinuxQuartz()
+	k, rec := newRecordingKBWrap(t, WithKeyMap(keyMap))
+
+	err := k.Send("{a down}{Down}{a up}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := SimpleKeyCode(platformKeys[KeyDown])
+	expected := []KeyEvent{
+		KeyDownEvent(keyMap['a']),
+		KeyDownEvent(down), KeyUpEvent(down),
+		KeyUpEvent(keyMap['a']),
+	}
+	events := rec.Events()
+	if len(events) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, events)
+	}
+	for i, e := range events {
+		if e.Down != expected[i].Down || e.Key != expected[i].Key {
+			t.Fatalf("event %d: expected %v, got %v", i, expected[i], e)
+		}
+	}
+}
+
+func TestSendModifierDownUp(t *testing.T) {
+	keyMap := KeyMapLinuxQuartz()
+	shift := KeyCode{ModifierSHIFT: true}
+	ctrl := KeyCode{ModifierCTRL: true}
+
+	tests := map[string][]KeyEvent{
+		"{Shift down}{Shift up}": {KeyDownEvent(shift), KeyUpEvent(shift)},
+		"{Shift down}a{Shift up}": {
+			KeyDownEvent(shift),
+			KeyDownEvent(keyMap['a']), KeyUpEvent(keyMap['a']),
+			KeyUpEvent(shift),
+		},
+		"{Ctrl down}{Shift down}{Ctrl up}{Shift up}": {
+			KeyDownEvent(ctrl), KeyDownEvent(shift), KeyUpEvent(ctrl), KeyUpEvent(shift),
+		},
+	}
+	for notation, expected := range tests {
+		t.Run(notation, func(t *testing.T) {
+			k, rec := newRecordingKBWrap(t, WithKeyMap(keyMap))
+			err := k.Send(notation)
+			if err != nil {
+				t.Fatal(err)
+			}
+			events := rec.Events()
+			if len(events) != len(expected) {
+				t.Fatalf("expected %v, got %v", expected, events)
+			}
+			for i, e := range events {
+				if e.Down != expected[i].Down || e.Key != expected[i].Key {
+					t.Fatalf("event %d: expected %v, got %v", i, expected[i], e)
+				}
+			}
+		})
+	}
+}
+
+func TestSendErrors(t *testing.T) {
+	tests := map[string]struct {
+		offset int
+		err    error
+	}{
+		"ab{Click}":  {2, ErrUnsupportedToken},
+		"{ENTER x}":  {0, ErrUnsupportedToken},
+		"x{Shift}":   {1, ErrUnsupportedToken},
+		"ab{ENTER":   {2, ErrInvalidSendNotation},
+		"a{":         {1, ErrInvalidSendNotation},
+		"a{}bc}":     {1, ErrInvalidSendNotation},
+		"a{}":        {1, ErrInvalidSendNotation},
+		"+(ab":       {1, ErrInvalidSendNotation},
+		"ab)":        {2, ErrInvalidSendNotation},
+		"ab^":        {3, ErrInvalidSendNotation},
+		"ab€":        {2, ErrKeyMappingNotFound},
+		"{U+20AC 2}": {0, ErrKeyMappingNotFound},
+	}
+	for notation, expected := range tests {
+		k, rec := newRecordingKBWrap(t, WithKeyMap(KeyMapLinuxQuartz()))
+		err := k.Send(notation)
+		var serr *SendError
+		if !errors.As(err, &serr) || !errors.Is(err, expected.err) {
+			t.Errorf("%q: expected %v, got %v", notation, expected.err, err)
+			continue
+		}
+		if serr.Offset != expected.offset {
+			t.Errorf("%q: expected offset %d, got %v", notation, expected.offset, err)
+		}
+		if len(rec.Events()) != 0 {
+			t.Errorf("%q: expected no events, got %v", notation, rec.Events())
+		}
+	}
+}
@@ -1,59 +1,112 @@
 package sendkeys
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
 	"runtime"
 	"sync"
 	"time"
-
-	kbd "github.com/micmonay/keybd_event"
 )
 
 // KBWrap is a wrapper for the keybd_event library for convenience
 type KBWrap struct {
-	d              kbd.KeyBonding
-	errors         []error
-	stubborn       bool
-	noisy          bool
-	random         bool
-	nodelay        bool
-	beforeDuration time.Duration
-	downDuration   time.Duration // how long the key is pressed
-	afterDuration  time.Duration // how long to wait after a key press
-
-	keyMap KeyMap
+	d           Backend
+	errors      []error
+	onError     func(err error)
+	onKeyEvent  func(e KeyEvent)
+	logger      *slog.Logger
+	stubborn    bool
+	noisy       bool
+	random      bool
+	nodelay     bool
+	beforeDelay Delay // how long to wait before a key press
+	downDelay   Delay // how long the key is pressed
+	afterDelay  Delay // how long to wait after a key press
+	rng         *rand.Rand
+	prev        KeyCode // previously typed key
+
+	keyMap    KeyMap
+	sequences KeySequences
+	keysyms   KeysymMap
+	fallback  Fallback
+
+	policy     UnmappedPolicy
+	substitute rune
+
+	held  []KeyCode // keys that are currently pressed, in the order they were pressed
+	stats Stats
+	char  *textChar // character whose key events are currently sent
+
+	dryRun *planWriter
+	clock  time.Duration // virtual time of the dry run
 
 	mu sync.Mutex
 }
 
 func newKbw() *KBWrap {
 	return &KBWrap{
-		errors:         []error{},
-		stubborn:       false,
-		noisy:          false,
-		random:         false,
-		nodelay:        false,
-		beforeDuration: 0 * time.Millisecond,
-		downDuration:   40 * time.Millisecond,
-		afterDuration:  10 * time.Millisecond,
-		keyMap:         defaultKeyMap(),
+		errors:      []error{},
+		stubborn:    false,
+		noisy:       false,
+		random:      false,
+		nodelay:     false,
+		beforeDelay: FixedDelay(0 * time.Millisecond),
+		downDelay:   FixedDelay(40 * time.Millisecond),
+		afterDelay:  FixedDelay(10 * time.Millisecond),
+		rng:         newRand(time.Now().UnixNano()),
+		substitute:  '?',
 	}
 }
 
 // NewKBWrapWithOptions creates a new keyboard wrapper with the given options.
 // As of writing, those options include: Stubborn Noisy and Random.
 // The defaults are all false.
+// In case no Backend is provided via WithBackend, the keybd_event library
+// is used in order to simulate key events on the local machine.
 func NewKBWrapWithOptions(opts ...KBOpt) (kbw *KBWrap, err error) {
 	kbw = newKbw()
-	kbw.d, err = kbd.NewKeyBonding()
-	if err != nil {
-		return nil, err
-	}
 	for _, opt := range opts {
 		opt(kbw)
 	}
+	if kbw.policy == UnmappedFallback && kbw.fallback == nil {
+		kbw.errors = append(kbw.errors, errors.New("unmapped policy fallback requires a Fallback"))
+	}
+	if len(kbw.errors) > 0 {
+		return nil, errors.Join(kbw.errors...)
+	}
+	if kbw.keyMap == nil && kbw.keysyms != nil {
+		kbw.keyMap = kbw.keysyms.KeyMap()
+	}
+	if kbw.keyMap == nil {
+		kbw.keyMap = defaultKeyMap()
+		if kbw.sequences == nil {
+			kbw.sequences = defaultKeySequences()
+		}
+	}
 
-	kbw.linDelay()
+	if kbw.noisy && kbw.logger == nil {
+		kbw.logger = slog.Default()
+	}
+
+	if kbw.random {
+		kbw.beforeDelay = JitterDelay(kbw.beforeDelay, 0.5)
+		kbw.downDelay = JitterDelay(kbw.downDelay, 0.5)
+		kbw.afterDelay = JitterDelay(kbw.afterDelay, 0.5)
+	}
+
+	if kbw.d == nil && kbw.dryRun != nil {
+		kbw.d = NewRecorder()
+	}
+	if kbw.d == nil {
+		kbw.d, err = newKbdBackend()
+		if err != nil {
+			return nil, err
+		}
+		kbw.linDelay()
+	}
 	return
 }
 
@@ -68,116 +121,316 @@ func (kb *KBWrap) linDelay() {
 	}
 }
 
-func (kb *KBWrap) down() {
-	if !kb.check() {
-		return
+// KeyMap returns the KeyMap that is used in order to translate characters into key codes.
+func (kb *KBWrap) KeyMap() KeyMap {
+	return kb.keyMap
+}
+
+// KeySequences returns the KeySequences that are used for characters which need
+// more than one key, e.g. dead keys.
+func (kb *KBWrap) KeySequences() KeySequences {
+	return kb.sequences
+}
+
+// Close releases all keys that are still held down and closes the underlying Backend.
+func (kb *KBWrap) Close() error {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+
+	return errors.Join(kb.releaseAll(), kb.d.Close())
+}
+
+// pressKey sends a key down event to the backend or, in dry run mode, to the plan.
+// Modifiers that are already held down are not sent again, see withoutHeldModifiers.
+// Errors are returned as *KeystrokeError and recorded.
+func (kb *KBWrap) pressKey(key KeyCode) error {
+	key = kb.withoutHeldModifiers(key)
+	var err error
+	if kb.dryRun != nil {
+		err = kb.dryRun.event(kb, true, key)
+	} else {
+		err = kb.d.Press(key)
+	}
+	if err == nil {
+		kb.sent(true, key)
 	}
-	kb.handle(kb.d.Press())
+	err = kb.keystrokeError(key, PhaseDown, err)
+	kb.handle(err)
+	return err
 }
-func (kb *KBWrap) up() {
+
+// releaseKey sends a key up event to the backend or, in dry run mode, to the plan.
+// Errors are returned as *KeystrokeError and recorded.
+func (kb *KBWrap) releaseKey(key KeyCode) error {
+	key = kb.withoutHeldModifiers(key)
+	var err error
+	if kb.dryRun != nil {
+		err = kb.dryRun.event(kb, false, key)
+	} else {
+		err = kb.d.Release(key)
+	}
+	if err == nil {
+		kb.sent(false, key)
+	}
+	err = kb.keystrokeError(key, PhaseUp, err)
+	kb.handle(err)
+	return err
+}
+
+// down presses the key unless previous errors prevent it.
+// pressed reports whether the key down event was sent.
+func (kb *KBWrap) down(key KeyCode) (pressed bool, err error) {
 	if !kb.check() {
-		return
+		return false, nil
 	}
-	kb.handle(kb.d.Release())
+	err = kb.pressKey(key)
+	kb.hold(key)
+	return true, err
+}
+func (kb *KBWrap) up(key KeyCode) error {
+	err := kb.releaseKey(key)
+	kb.unhold(key)
+	return err
 }
 
-// press presses a key from the queue, waits, and then releases.
-// Default wait time is 10 milliseconds.
-func (kb *KBWrap) press() {
-	if kb.beforeDuration > 0 {
-		time.Sleep(kb.beforeDuration)
+// sleep waits for the given duration or until the context is done.
+func (kb *KBWrap) sleep(ctx context.Context, d time.Duration) error {
+	if kb.dryRun != nil {
+		if d > 0 {
+			kb.clock += d
+		}
+		return ctx.Err()
+	}
+	if d <= 0 {
+		return ctx.Err()
 	}
-	kb.down()
-	time.Sleep(kb.downDuration)
-	kb.up()
-	if kb.afterDuration > 0 {
-		time.Sleep(kb.afterDuration)
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
 	}
 }
 
-func (kb *KBWrap) set(keys ...int) {
-	kb.d.SetKeys(keys...)
+// press presses a key, waits, and then releases it.
+// Default wait time is 10 milliseconds.
+// In case the context is cancelled while the key is held down, the key is released immediately.
+// pressed reports whether the key was sent before the context was cancelled.
+func (kb *KBWrap) press(ctx context.Context, key KeyCode) (pressed bool, err error) {
+	return kb.send(ctx, Tap(key))
+}
+
+// send sends the key events that produce a single character.
+// A key down event is preceded by the before delay and followed by the down delay,
+// a key up event is followed by the after delay.
+// Once the first event was sent, the remaining events are sent as well, so that no key
+// is left pressed and no dead key is left pending. In case the context is cancelled,
+// all remaining delays are skipped.
+// sent reports whether the events were sent before the context was cancelled.
+// The errors of the key events are returned together with the error of the context.
+func (kb *KBWrap) send(ctx context.Context, events []KeyEvent) (sent bool, err error) {
+	defer kb.releaseOnPanic()
+
+	var (
+		// previously typed key of every pressed key
+		held    = map[KeyCode]KeyCode{}
+		skipped = map[KeyCode]bool{}
+		errs    []error
+	)
+	for i, e := range events {
+		if !e.Down {
+			if skipped[e.Key] {
+				delete(skipped, e.Key)
+				continue
+			}
+			prev := held[e.Key]
+			delete(held, e.Key)
+			errs = append(errs, kb.up(e.Key))
+			_ = kb.delay(ctx, phaseAfter, kb.afterDelay.Next(kb.rng, prev, e.Key))
+			continue
+		}
+
+		prev := kb.prev
+		err = kb.delay(ctx, phaseBefore, kb.beforeDelay.Next(kb.rng, prev, e.Key))
+		if i == 0 && err != nil {
+			return false, err
+		}
+		kb.prev = e.Key
+		pressed, err := kb.down(e.Key)
+		if !pressed {
+			skipped[e.Key] = true
+			continue
+		}
+		errs = append(errs, err)
+		held[e.Key] = prev
+		_ = kb.delay(ctx, phaseDown, kb.downDelay.Next(kb.rng, prev, e.Key))
+	}
+	return true, errors.Join(append(errs, ctx.Err())...)
 }
 
-func (kb *KBWrap) clr() {
-	kb.d.Clear()
+func (kb *KBWrap) only(ctx context.Context, k Key) error {
+	code, err := kb.namedKey(k)
+	if err != nil {
+		return err
+	}
+	_, err = kb.press(ctx, code)
+	return err
 }
 
-func (kb *KBWrap) only(k int) {
-	kb.clr()
-	kb.set(k)
-	kb.press()
-	kb.clr()
+// namedKey resolves the named key to a keysym in case a KeysymMap is used
+// and to the key code of the current platform otherwise.
+func (kb *KBWrap) namedKey(k Key) (KeyCode, error) {
+	if kb.keysyms == nil {
+		return k.KeyCode()
+	}
+	ks, ok := k.Keysym()
+	if !ok {
+		return KeyCode{}, fmt.Errorf("%w: %v", ErrNoKeysym, k)
+	}
+	return KeysymKeyCode(ks), nil
 }
 
 // Escape presses the escape key.
 // All other keys will be cleared.
 func (kb *KBWrap) Escape() {
+	_ = kb.EscapeContext(context.Background())
+}
+
+// EscapeContext presses the escape key unless the context is cancelled.
+func (kb *KBWrap) EscapeContext(ctx context.Context) error {
 	kb.mu.Lock()
 	defer kb.mu.Unlock()
 
-	kb.only(kbd.VK_ESC)
+	return kb.only(ctx, KeyEscape)
 }
 
 // Tab presses the tab key.
 // All other keys will be cleared.
 func (kb *KBWrap) Tab() {
+	_ = kb.TabContext(context.Background())
+}
+
+// TabContext presses the tab key unless the context is cancelled.
+func (kb *KBWrap) TabContext(ctx context.Context) error {
 	kb.mu.Lock()
 	defer kb.mu.Unlock()
 
-	kb.only(kbd.VK_TAB)
+	return kb.only(ctx, KeyTab)
 }
 
 // Enter presses the enter key.
 // All other keys will be cleared.
 func (kb *KBWrap) Enter() {
+	_ = kb.EnterContext(context.Background())
+}
+
+// EnterContext presses the enter key unless the context is cancelled.
+func (kb *KBWrap) EnterContext(ctx context.Context) error {
 	kb.mu.Lock()
 	defer kb.mu.Unlock()
 
-	kb.only(kbd.VK_ENTER)
+	return kb.only(ctx, KeyEnter)
 }
 
 // BackSpace presses the backspace key.
 // All other keys will be cleared.
 func (kb *KBWrap) BackSpace() {
+	_ = kb.BackSpaceContext(context.Background())
+}
+
+// BackSpaceContext presses the backspace key unless the context is cancelled.
+func (kb *KBWrap) BackSpaceContext(ctx context.Context) error {
 	kb.mu.Lock()
 	defer kb.mu.Unlock()
 
-	kb.only(backspace)
+	return kb.only(ctx, KeyBackSpace)
 }
 
 // Type types out a string by simulating keystrokes.
 // Check the exported Symbol map for non-alphanumeric keys.
 func (kb *KBWrap) Type(s string) error {
-	keys := kb.strToKeys(s)
-	if !kb.check() {
-		return errors.Join(kb.errors...)
+	_, err := kb.TypeContext(context.Background(), s)
+	return err
+}
+
+// TypeContext types out a string by simulating keystrokes until the context is cancelled.
+// The context is checked between keystrokes and a key that is held down is always released.
+// n is the number of characters that were sent before the context was cancelled.
+// Characters that cannot be typed are handled according to the UnmappedPolicy,
+// by default an *UnmappedError is returned for each of them before any key is pressed.
+// Key events that cannot be sent are returned as *KeystrokeError. Unless Stubborn is set,
+// typing stops at the first of them.
+func (kb *KBWrap) TypeContext(ctx context.Context, s string) (n int, err error) {
+	return kb.typeContext(ctx, s, KeyCode{}, nil)
+}
+
+// typeContext types the string while adding the modifiers of mods to every key.
+// progress is optional.
+func (kb *KBWrap) typeContext(ctx context.Context, s string, mods KeyCode, progress ProgressFunc) (n int, err error) {
+	if kb.dryRun != nil {
+		return kb.planContext(ctx, s, mods, progress)
+	}
+
+	keys, chars, errs := kb.resolve(s)
+	if len(errs) > 0 {
+		return 0, errors.Join(errs...)
 	}
 
 	kb.mu.Lock()
 	defer kb.mu.Unlock()
 
-	for _, key := range keys {
-		kb.d.HasALT(key.ModifierALT)
-		kb.d.HasSuper(key.ModifierSuper)
-		kb.d.HasCTRL(key.ModifierCTRL)
-		kb.d.HasSHIFT(key.ModifierSHIFT)
-		kb.set(key.Code)
-		kb.press()
-		kb.clr()
+	if !kb.check() {
+		return 0, errors.Join(kb.errors...)
+	}
+	return kb.typeKeys(ctx, keys, chars, mods, progress)
+}
+
+// typeKeys sends the key events of every character while adding the modifiers of mods to every key.
+// Unless Stubborn is set, typing stops at the first error.
+func (kb *KBWrap) typeKeys(ctx context.Context, keys [][]KeyEvent, chars []textChar, mods KeyCode, progress ProgressFunc) (n int, err error) {
+	defer func() {
+		kb.char = nil
+	}()
+	tracker := kb.newProgressTracker(progress, len(keys))
+	tracker.report(0)
+
+	var errs []error
+	for i, events := range keys {
+		if mods != (KeyCode{}) {
+			for j := range events {
+				events[j].Key = withModifiers(events[j].Key, mods)
+			}
+		}
+		kb.char = &chars[i]
+		sent, err := kb.send(ctx, events)
+		if sent {
+			n++
+			kb.stats.Chars++
+			tracker.report(n)
+		}
+		if err != nil && (!kb.stubborn || ctx.Err() != nil) {
+			return n, err
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
 	}
-	return nil
+	return n, errors.Join(errs...)
 }
 
+// TypeRaw presses the given key code with its modifiers.
 func (kb *KBWrap) TypeRaw(key KeyCode) {
+	_ = kb.TypeRawContext(context.Background(), key)
+}
+
+// TypeRawContext presses the given key code with its modifiers unless the context is cancelled.
+func (kb *KBWrap) TypeRawContext(ctx context.Context, key KeyCode) error {
 	kb.mu.Lock()
 	defer kb.mu.Unlock()
 
-	kb.d.HasALT(key.ModifierALT)
-	kb.d.HasSuper(key.ModifierSuper)
-	kb.d.HasCTRL(key.ModifierCTRL)
-	kb.d.HasSHIFT(key.ModifierSHIFT)
-	kb.set(key.Code)
-	kb.press()
-	kb.clr()
+	_, err := kb.press(ctx, key)
+	return err
 }
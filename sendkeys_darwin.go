@@ -2,6 +2,90 @@ package sendkeys
 
 import kbd "github.com/micmonay/keybd_event"
 
-const (
-	backspace = kbd.VK_DELETE
+const altKey = kbd.VK_Option
+
+// numpadKeys are the key codes of the numeric keypad digits 0 to 9.
+var numpadKeys = [10]int{
+	kbd.VK_Keypad0, kbd.VK_Keypad1, kbd.VK_Keypad2, kbd.VK_Keypad3, kbd.VK_Keypad4,
+	kbd.VK_Keypad5, kbd.VK_Keypad6, kbd.VK_Keypad7, kbd.VK_Keypad8, kbd.VK_Keypad9,
+}
+
+// leftModifierKeys and rightModifierKeys are not set, because macOS applications
+// only honour the modifier flags of key events, which do not distinguish sides.
+var (
+	leftModifierKeys  []int
+	rightModifierKeys []int
 )
+
+// modifierKeys are the key codes of the modifier keys that are pressed for key codes
+// without a key, in the order super, alt, ctrl, shift and AltGr. Command, Control and
+// Shift are not exported by keybd_event.
+var modifierKeys = [5]int{0x37, kbd.VK_Option, 0x3b, 0x38, kbd.VK_Option}
+
+// platformKeys maps the named keys to macOS virtual key codes.
+// PrintScreen, ScrollLock, Pause, Menu, F21-F24 and the media transport keys
+// do not exist as regular key codes on macOS.
+var platformKeys = map[Key]int{
+	KeyEscape:    kbd.VK_ESC,
+	KeyTab:       kbd.VK_TAB,
+	KeyEnter:     kbd.VK_ENTER,
+	KeyBackSpace: kbd.VK_DELETE,
+	KeySpace:     kbd.VK_SPACE,
+	KeyCapsLock:  kbd.VK_CAPSLOCK,
+
+	KeyInsert:   kbd.VK_HELP, // Help is located where PC keyboards have Insert
+	KeyDelete:   kbd.VK_ForwardDelete,
+	KeyHome:     kbd.VK_HOME,
+	KeyEnd:      kbd.VK_END,
+	KeyPageUp:   kbd.VK_PAGEUP,
+	KeyPageDown: kbd.VK_PAGEDOWN,
+
+	KeyUp:    kbd.VK_UP,
+	KeyDown:  kbd.VK_DOWN,
+	KeyLeft:  kbd.VK_LEFT,
+	KeyRight: kbd.VK_RIGHT,
+
+	KeyF1:  kbd.VK_F1,
+	KeyF2:  kbd.VK_F2,
+	KeyF3:  kbd.VK_F3,
+	KeyF4:  kbd.VK_F4,
+	KeyF5:  kbd.VK_F5,
+	KeyF6:  kbd.VK_F6,
+	KeyF7:  kbd.VK_F7,
+	KeyF8:  kbd.VK_F8,
+	KeyF9:  kbd.VK_F9,
+	KeyF10: kbd.VK_F10,
+	KeyF11: kbd.VK_F11,
+	KeyF12: kbd.VK_F12,
+	KeyF13: kbd.VK_F13,
+	KeyF14: kbd.VK_F14,
+	KeyF15: kbd.VK_F15,
+	KeyF16: kbd.VK_F16,
+	KeyF17: kbd.VK_F17,
+	KeyF18: kbd.VK_F18,
+	KeyF19: kbd.VK_F19,
+	KeyF20: kbd.VK_F20,
+
+	KeyNumLock:        kbd.VK_KeypadClear, // Clear is located where PC keyboards have NumLock
+	KeyNumpad0:        kbd.VK_Keypad0,
+	KeyNumpad1:        kbd.VK_Keypad1,
+	KeyNumpad2:        kbd.VK_Keypad2,
+	KeyNumpad3:        kbd.VK_Keypad3,
+	KeyNumpad4:        kbd.VK_Keypad4,
+	KeyNumpad5:        kbd.VK_Keypad5,
+	KeyNumpad6:        kbd.VK_Keypad6,
+	KeyNumpad7:        kbd.VK_Keypad7,
+	KeyNumpad8:        kbd.VK_Keypad8,
+	KeyNumpad9:        kbd.VK_Keypad9,
+	KeyNumpadAdd:      kbd.VK_KeypadPlus,
+	KeyNumpadSubtract: kbd.VK_KeypadMinus,
+	KeyNumpadMultiply: kbd.VK_KeypadMultiply,
+	KeyNumpadDivide:   kbd.VK_KeypadDivide,
+	KeyNumpadDecimal:  kbd.VK_KeypadDecimal,
+	KeyNumpadEnter:    kbd.VK_KeypadEnter,
+	KeyNumpadEqual:    kbd.VK_KeypadEquals,
+
+	KeyVolumeMute: kbd.VK_MUTE,
+	KeyVolumeDown: kbd.VK_VOLUMEDOWN,
+	KeyVolumeUp:   kbd.VK_VOLUMEUP,
+}
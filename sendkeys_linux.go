@@ -3,6 +3,99 @@ package sendkeys
 //go: build +linux
 import kbd "github.com/micmonay/keybd_event"
 
-const (
-	backspace = kbd.VK_BACKSPACE
+const altKey = 56 // left alt, not exported by keybd_event
+
+// numpadKeys are the key codes of the numeric keypad digits 0 to 9.
+var numpadKeys = [10]int{
+	kbd.VK_KP0, kbd.VK_KP1, kbd.VK_KP2, kbd.VK_KP3, kbd.VK_KP4,
+	kbd.VK_KP5, kbd.VK_KP6, kbd.VK_KP7, kbd.VK_KP8, kbd.VK_KP9,
+}
+
+// leftModifierKeys and rightModifierKeys are the key codes of the modifier keys
+// in the order super, alt, ctrl, shift.
+var (
+	leftModifierKeys  = []int{125, 56, 29, 42}
+	rightModifierKeys = []int{126, 100, 97, 54}
 )
+
+// modifierKeys are the key codes of the modifier keys that are pressed for key codes
+// without a key, in the order super, alt, ctrl, shift and AltGr.
+var modifierKeys = [5]int{125, 56, 29, 42, 100}
+
+// platformKeys maps the named keys to evdev key codes.
+var platformKeys = map[Key]int{
+	KeyEscape:      kbd.VK_ESC,
+	KeyTab:         kbd.VK_TAB,
+	KeyEnter:       kbd.VK_ENTER,
+	KeyBackSpace:   kbd.VK_BACKSPACE,
+	KeySpace:       kbd.VK_SPACE,
+	KeyCapsLock:    kbd.VK_CAPSLOCK,
+	KeyPrintScreen: kbd.VK_SYSRQ,
+	KeyScrollLock:  kbd.VK_SCROLLLOCK,
+	KeyPause:       kbd.VK_PAUSE,
+	KeyMenu:        kbd.VK_COMPOSE, // context menu key of PC keyboards
+
+	KeyInsert:   kbd.VK_INSERT,
+	KeyDelete:   kbd.VK_DELETE,
+	KeyHome:     kbd.VK_HOME,
+	KeyEnd:      kbd.VK_END,
+	KeyPageUp:   kbd.VK_PAGEUP,
+	KeyPageDown: kbd.VK_PAGEDOWN,
+
+	KeyUp:    kbd.VK_UP,
+	KeyDown:  kbd.VK_DOWN,
+	KeyLeft:  kbd.VK_LEFT,
+	KeyRight: kbd.VK_RIGHT,
+
+	KeyF1:  kbd.VK_F1,
+	KeyF2:  kbd.VK_F2,
+	KeyF3:  kbd.VK_F3,
+	KeyF4:  kbd.VK_F4,
+	KeyF5:  kbd.VK_F5,
+	KeyF6:  kbd.VK_F6,
+	KeyF7:  kbd.VK_F7,
+	KeyF8:  kbd.VK_F8,
+	KeyF9:  kbd.VK_F9,
+	KeyF10: kbd.VK_F10,
+	KeyF11: kbd.VK_F11,
+	KeyF12: kbd.VK_F12,
+	KeyF13: kbd.VK_F13,
+	KeyF14: kbd.VK_F14,
+	KeyF15: kbd.VK_F15,
+	KeyF16: kbd.VK_F16,
+	KeyF17: kbd.VK_F17,
+	KeyF18: kbd.VK_F18,
+	KeyF19: kbd.VK_F19,
+	KeyF20: kbd.VK_F20,
+	KeyF21: kbd.VK_F21,
+	KeyF22: kbd.VK_F22,
+	KeyF23: kbd.VK_F23,
+	KeyF24: kbd.VK_F24,
+
+	KeyNumLock:        kbd.VK_NUMLOCK,
+	KeyNumpad0:        kbd.VK_KP0,
+	KeyNumpad1:        kbd.VK_KP1,
+	KeyNumpad2:        kbd.VK_KP2,
+	KeyNumpad3:        kbd.VK_KP3,
+	KeyNumpad4:        kbd.VK_KP4,
+	KeyNumpad5:        kbd.VK_KP5,
+	KeyNumpad6:        kbd.VK_KP6,
+	KeyNumpad7:        kbd.VK_KP7,
+	KeyNumpad8:        kbd.VK_KP8,
+	KeyNumpad9:        kbd.VK_KP9,
+	KeyNumpadAdd:      kbd.VK_KPPLUS,
+	KeyNumpadSubtract: kbd.VK_KPMINUS,
+	KeyNumpadMultiply: kbd.VK_KPASTERISK,
+	KeyNumpadDivide:   kbd.VK_KPSLASH,
+	KeyNumpadDecimal:  kbd.VK_KPDOT,
+	KeyNumpadEnter:    kbd.VK_KPENTER,
+	KeyNumpadEqual:    kbd.VK_KPEQUAL,
+
+	KeyVolumeMute:     kbd.VK_MUTE,
+	KeyVolumeDown:     kbd.VK_VOLUMEDOWN,
+	KeyVolumeUp:       kbd.VK_VOLUMEUP,
+	KeyMediaPlayPause: kbd.VK_PLAYPAUSE,
+	KeyMediaStop:      kbd.VK_STOPCD,
+	KeyMediaNext:      kbd.VK_NEXTSONG,
+	KeyMediaPrevious:  kbd.VK_PREVIOUSSONG,
+}
@@ -178,13 +178,71 @@ func listenForKeys(t *testing.T, ret chan rune) {
 
 func strTo(teststr string, t *testing.T) {
 	split := strings.Split(teststr, "")
-	k, err := NewKBWrapWithOptions(Noisy, NoDelay)
+	k, err := NewKBWrapWithOptions(Noisy, NoDelay, WithBackend(NewRecorder()))
 	if err != nil {
 		t.Fatalf(err.Error())
 	}
-	keys := k.strToKeys(teststr)
+	keys, err := k.strToKeys(teststr)
+	if err != nil {
+		t.Fatal(err)
+	}
 	if len(keys) != len(split) {
 		t.Fatalf("length of mapped keys: %d, wanted length of string: %d", len(keys), len(split))
 	}
 	t.Logf("string: %s, keys: %#v", teststr, keys)
 }
+
+// cancelBackend cancels the context after the given number of key presses.
+type cancelBackend struct {
+	*Recorder
+	after  int
+	cancel context.CancelFunc
+}
+
+func (b *cancelBackend) Press(key KeyCode) error {
+	b.after--
+	if b.after == 0 {
+		b.cancel()
+	}
+	return b.Recorder.Press(key)
+}
+
+func TestTypeContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rec := NewRecorder()
+	k, err := NewKBWrapWithOptions(
+		WithBackend(&cancelBackend{Recorder: rec, after: 1, cancel: cancel}),
+		WithKeyMap(KeyMapLinuxQuartz()),
+		KeystrokeDuration(time.Hour),
+		DelayAfter(0),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := k.TypeContext(ctx, "yeet town")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 sent character, got %d", n)
+	}
+
+	typed, err := rec.Text(k.KeyMap())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typed != "y" {
+		t.Fatalf("expected all pressed keys to be released, got: %q", typed)
+	}
+
+	err = k.EnterContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+	if len(rec.Events()) != 2 {
+		t.Fatalf("expected no further events after cancellation, got %d", len(rec.Events()))
+	}
+}
@@ -0,0 +1,173 @@
+package sendkeys
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrAborted is returned by Session.Wait after the session was aborted.
+var ErrAborted = errors.New("typing aborted")
+
+// Session is a text that is typed in the background, see Start.
+// The KBWrap is only locked while a character is typed, so that it can be used
+// in between, e.g. while the session is paused.
+type Session struct {
+	kb     *KBWrap
+	cancel context.CancelCauseFunc
+	done   chan struct{}
+
+	mu     sync.Mutex
+	resume chan struct{} // closed on Resume, nil unless paused
+	n      int
+	err    error
+}
+
+// Start types out a string in the background.
+func (kb *KBWrap) Start(s string) *Session {
+	return kb.StartContext(context.Background(), s)
+}
+
+// StartContext types out a string in the background until the context is cancelled
+// or the session is aborted. Characters that cannot be typed are handled like in TypeContext.
+func (kb *KBWrap) StartContext(ctx context.Context, s string) *Session {
+	ctx, cancel := context.WithCancelCause(ctx)
+	session := &Session{
+		kb:     kb,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go session.run(ctx, s)
+	return session
+}
+
+// Pause stops typing before the next character until Resume is called.
+func (s *Session) Pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.resume == nil {
+		s.resume = make(chan struct{})
+	}
+}
+
+// Resume continues typing after Pause.
+func (s *Session) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.resume != nil {
+		close(s.resume)
+		s.resume = nil
+	}
+}
+
+// Paused reports whether the session is paused.
+func (s *Session) Paused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.resume != nil
+}
+
+// Abort stops typing, also while the session is paused.
+// The current keystroke is completed without delays and all held keys are released.
+// Abort does not wait for the session to end, see Wait.
+func (s *Session) Abort() {
+	s.cancel(ErrAborted)
+}
+
+// Done returns a channel that is closed when the session has ended.
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Sent returns the number of characters that were sent so far.
+func (s *Session) Sent() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.n
+}
+
+// Wait waits for the session to end and returns the number of characters that were sent.
+// The error is ErrAborted in case the session was aborted.
+func (s *Session) Wait() (n int, err error) {
+	<-s.done
+	return s.n, s.err
+}
+
+// wait blocks while the session is paused.
+func (s *Session) wait(ctx context.Context) error {
+	s.mu.Lock()
+	resume := s.resume
+	s.mu.Unlock()
+
+	if resume == nil {
+		return context.Cause(ctx)
+	}
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-resume:
+		return nil
+	}
+}
+
+func (s *Session) run(ctx context.Context, text string) {
+	defer close(s.done)
+	defer s.cancel(nil)
+
+	s.err = s.typeText(ctx, text)
+}
+
+// typeText types the text character by character and locks the KBWrap for every character.
+func (s *Session) typeText(ctx context.Context, text string) error {
+	kb := s.kb
+	keys, chars, errs := kb.resolve(text)
+	if len(errs) > 0 {
+		if kb.dryRun != nil {
+			kb.mu.Lock()
+			errs = append(errs, kb.planUnmapped(errs))
+			kb.mu.Unlock()
+		}
+		return errors.Join(errs...)
+	}
+
+	for i := range keys {
+		err := s.wait(ctx)
+		if err != nil {
+			return s.stop(err)
+		}
+
+		kb.mu.Lock()
+		n, err := 0, errors.Join(kb.errors...)
+		if kb.check() {
+			n, err = kb.typeKeys(ctx, keys[i:i+1], chars[i:i+1], KeyCode{}, nil)
+		}
+		kb.mu.Unlock()
+
+		s.mu.Lock()
+		s.n += n
+		s.mu.Unlock()
+
+		if ctx.Err() != nil {
+			return s.stop(context.Cause(ctx))
+		}
+		if err != nil && !kb.stubborn {
+			return err
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// stop releases all held keys after the session was aborted.
+func (s *Session) stop(err error) error {
+	s.kb.mu.Lock()
+	defer s.kb.mu.Unlock()
+
+	return errors.Join(err, s.kb.releaseAll())
+}
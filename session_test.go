@@ -0,0 +1,135 @@
+package sendkeys
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// newPausingSession starts typing the text and pauses the session after the first key up event.
+func newPausingSession(t *testing.T, text string, opts ...KBOpt) (*KBWrap, *Recorder, *Session) {
+	t.Helper()
+	sessions := make(chan *Session, 1)
+	paused := false
+	opts = append(opts,
+		WithKeyMap(KeyMap{'a': SimpleKeyCode(30), 'b': SimpleKeyCode(48), 'c': SimpleKeyCode(46)}),
+		OnKeyEvent(func(e KeyEvent) {
+			if !e.Down && !paused {
+				paused = true
+				(<-sessions).Pause()
+			}
+		}),
+	)
+	k, rec := newRecordingKBWrap(t, opts...)
+	s := k.Start(text)
+	sessions <- s
+
+	deadline := time.Now().Add(5 * time.Second)
+	for s.Sent() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return k, rec, s
+}
+
+func TestSessionPauseResume(t *testing.T) {
+	k, rec, s := newPausingSession(t, "abc")
+
+	time.Sleep(20 * time.Millisecond)
+	if !s.Paused() {
+		t.Error("expected paused session")
+	}
+	if got := len(rec.Events()); got != 2 {
+		t.Fatalf("expected 2 events while paused, got %d", got)
+	}
+	// the KBWrap can be used while the session is paused
+	err := k.Type("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Resume()
+	n, err := s.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("expected 3 characters, got %d", n)
+	}
+	text, err := rec.Text(k.KeyMap())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "abbc" {
+		t.Errorf("expected abbc, got %q", text)
+	}
+}
+
+func TestSessionAbortWhilePaused(t *testing.T) {
+	k, rec, s := newPausingSession(t, "abc")
+	shift := SimpleKeyCode(42)
+	err := k.KeyDown(shift)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Abort()
+	n, err := s.Wait()
+	if !errors.Is(err, ErrAborted) {
+		t.Errorf("expected ErrAborted, got %v", err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 character, got %d", n)
+	}
+	if held := k.Held(); len(held) != 0 {
+		t.Errorf("expected all keys to be released, got %v", held)
+	}
+	events := rec.Events()
+	if last := events[len(events)-1]; last.Down || last.Key != shift {
+		t.Errorf("expected shift to be released, got %v", events)
+	}
+}
+
+func TestSessionAbortWhileKeyIsDown(t *testing.T) {
+	pressed := make(chan struct{}, 1)
+	k, rec := newRecordingKBWrap(t,
+		WithKeyMap(KeyMap{'a': SimpleKeyCode(30), 'b': SimpleKeyCode(48)}),
+		KeystrokeDuration(time.Hour),
+		OnKeyEvent(func(e KeyEvent) {
+			if e.Down {
+				pressed <- struct{}{}
+			}
+		}),
+	)
+
+	s := k.Start("ab")
+	<-pressed
+	s.Abort()
+	select {
+	case <-s.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+	n, err := s.Wait()
+	if !errors.Is(err, ErrAborted) || n != 1 {
+		t.Errorf("expected ErrAborted after 1 character, got %d, %v", n, err)
+	}
+	if events := rec.Events(); len(events) != 2 || events[1].Down {
+		t.Errorf("expected the key to be released, got %v", events)
+	}
+}
+
+func TestSessionUnmapped(t *testing.T) {
+	k, rec := newRecordingKBWrap(t, WithKeyMap(KeyMap{'a': SimpleKeyCode(30)}))
+
+	_, err := k.Start("ab").Wait()
+	var uerr *UnmappedError
+	if !errors.As(err, &uerr) || uerr.Rune != 'b' {
+		t.Errorf("expected *UnmappedError, got %v", err)
+	}
+	if len(rec.Events()) != 0 {
+		t.Errorf("expected no events, got %v", rec.Events())
+	}
+}
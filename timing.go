@@ -0,0 +1,128 @@
+package sendkeys
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Delay computes how long to wait in one phase of a keystroke:
+// before a key is pressed, while it is held down or after it was released.
+// prev is the previously typed key and key is the key that is currently typed.
+// The random number generator is owned by the KBWrap and may be used
+// in order to produce reproducible jitter.
+type Delay interface {
+	Next(r *rand.Rand, prev, key KeyCode) time.Duration
+}
+
+// DelayFunc allows to use a plain function as Delay.
+type DelayFunc func(r *rand.Rand, prev, key KeyCode) time.Duration
+
+func (f DelayFunc) Next(r *rand.Rand, prev, key KeyCode) time.Duration {
+	return f(r, prev, key)
+}
+
+// FixedDelay always waits for the same duration.
+func FixedDelay(d time.Duration) Delay {
+	return DelayFunc(func(*rand.Rand, KeyCode, KeyCode) time.Duration {
+		return d
+	})
+}
+
+// UniformDelay waits for a uniformly distributed duration in the range [min, max].
+func UniformDelay(min, max time.Duration) Delay {
+	if min > max {
+		min, max = max, min
+	}
+	return DelayFunc(func(r *rand.Rand, _, _ KeyCode) time.Duration {
+		return min + time.Duration(r.Int63n(int64(max-min)+1))
+	})
+}
+
+// GaussianDelay waits for a normally distributed duration.
+// Negative durations are clamped to 0.
+func GaussianDelay(mean, stddev time.Duration) Delay {
+	return DelayFunc(func(r *rand.Rand, _, _ KeyCode) time.Duration {
+		return clampDelay(time.Duration(r.NormFloat64()*float64(stddev)) + mean)
+	})
+}
+
+// JitterDelay varies the durations of the given Delay uniformly
+// by the given factor, e.g. a factor of 0.5 returns a duration
+// between 50% and 150% of the original duration.
+func JitterDelay(d Delay, factor float64) Delay {
+	factor = math.Abs(factor)
+	return DelayFunc(func(r *rand.Rand, prev, key KeyCode) time.Duration {
+		base := float64(d.Next(r, prev, key))
+		return clampDelay(time.Duration(base * (1 - factor + 2*factor*r.Float64())))
+	})
+}
+
+// KeyPair is a pair of consecutively typed keys.
+type KeyPair struct {
+	Prev KeyCode
+	Key  KeyCode
+}
+
+// HumanDelay is a Delay that models the rhythm of a human typist.
+// Specific key pairs can be made faster or slower than the base delay,
+// changing modifiers and repeating the same key take additional time.
+type HumanDelay struct {
+	// Base is used for all key pairs that have no explicit entry in Pairs.
+	Base Delay
+	// Pairs overrides the delay for specific key pairs.
+	Pairs map[KeyPair]Delay
+	// ModifierPenalty is added when the modifiers of two consecutive keys differ.
+	ModifierPenalty time.Duration
+	// RepeatPenalty is added when the same key is typed twice in a row.
+	RepeatPenalty time.Duration
+}
+
+// HumanTypist creates a HumanDelay profile with a normally distributed base delay
+// around the given mean.
+func HumanTypist(mean time.Duration) *HumanDelay {
+	return &HumanDelay{
+		Base:            GaussianDelay(mean, mean/4),
+		Pairs:           map[KeyPair]Delay{},
+		ModifierPenalty: mean / 2,
+		RepeatPenalty:   mean / 4,
+	}
+}
+
+func (h *HumanDelay) Next(r *rand.Rand, prev, key KeyCode) time.Duration {
+	d, ok := h.Pairs[KeyPair{Prev: prev, Key: key}]
+	if !ok {
+		d = h.Base
+	}
+
+	result := time.Duration(0)
+	if d != nil {
+		result = d.Next(r, prev, key)
+	}
+	if !sameModifiers(prev, key) {
+		result += h.ModifierPenalty
+	}
+	if prev == key {
+		result += h.RepeatPenalty
+	}
+	return result
+}
+
+func sameModifiers(a, b KeyCode) bool {
+	return a.ModifierSuper == b.ModifierSuper &&
+		a.ModifierALT == b.ModifierALT &&
+		a.ModifierCTRL == b.ModifierCTRL &&
+		a.ModifierSHIFT == b.ModifierSHIFT &&
+		a.ModifierALTGR == b.ModifierALTGR
+}
+
+func clampDelay(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+func newRand(seed int64) *rand.Rand {
+	return rand.New(rand.NewSource(seed))
+}
@@ -0,0 +1,80 @@
+package sendkeys
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUniformDelay(t *testing.T) {
+	var (
+		r     = newRand(42)
+		delay = UniformDelay(20*time.Millisecond, 10*time.Millisecond)
+	)
+	for i := 0; i < 1000; i++ {
+		d := delay.Next(r, KeyCode{}, KeyCode{})
+		if d < 10*time.Millisecond || d > 20*time.Millisecond {
+			t.Fatalf("delay out of range: %s", d)
+		}
+	}
+}
+
+func TestGaussianDelayClamp(t *testing.T) {
+	var (
+		r     = newRand(42)
+		delay = GaussianDelay(0, time.Second)
+	)
+	for i := 0; i < 1000; i++ {
+		if d := delay.Next(r, KeyCode{}, KeyCode{}); d < 0 {
+			t.Fatalf("negative delay: %s", d)
+		}
+	}
+}
+
+func TestDelaySeedReproducible(t *testing.T) {
+	var (
+		delay = JitterDelay(HumanTypist(100*time.Millisecond), 0.5)
+		a     = newRand(1337)
+		b     = newRand(1337)
+		prev  = SimpleKeyCode(1)
+		key   = ShiftKeyCode(2)
+	)
+	for i := 0; i < 100; i++ {
+		da := delay.Next(a, prev, key)
+		db := delay.Next(b, prev, key)
+		if da != db {
+			t.Fatalf("expected identical delays with identical seeds, got %s and %s", da, db)
+		}
+	}
+}
+
+func TestHumanDelay(t *testing.T) {
+	var (
+		r = newRand(0)
+		a = SimpleKeyCode(1)
+		b = SimpleKeyCode(2)
+		B = ShiftKeyCode(2)
+		h = &HumanDelay{
+			Base: FixedDelay(100 * time.Millisecond),
+			Pairs: map[KeyPair]Delay{
+				{Prev: a, Key: b}: FixedDelay(50 * time.Millisecond),
+			},
+			ModifierPenalty: 30 * time.Millisecond,
+			RepeatPenalty:   20 * time.Millisecond,
+		}
+	)
+
+	tests := []struct {
+		prev, key KeyCode
+		expected  time.Duration
+	}{
+		{a, b, 50 * time.Millisecond},
+		{b, a, 100 * time.Millisecond},
+		{a, B, 130 * time.Millisecond},
+		{a, a, 120 * time.Millisecond},
+	}
+	for _, test := range tests {
+		if d := h.Next(r, test.prev, test.key); d != test.expected {
+			t.Errorf("%s -> %s: expected %s, got %s", test.prev, test.key, test.expected, d)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package sendkeys
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// UnmappedRune is a character that cannot be typed, together with its position in the text.
+type UnmappedRune struct {
+	Rune   rune `json:"rune"`
+	Offset int  `json:"offset"` // byte offset
+	Line   int  `json:"line"`   // starts at 1
+	Col    int  `json:"col"`    // starts at 1, counted in characters
+}
+
+func (u UnmappedRune) String() string {
+	return fmt.Sprintf("%d:%d: %q (%U) at offset %d", u.Line, u.Col, u.Rune, u.Rune, u.Offset)
+}
+
+// UnmappedError is returned for characters that cannot be typed.
+// It wraps ErrKeyMappingNotFound and the cause, e.g. the error of a Fallback.
+type UnmappedError struct {
+	UnmappedRune
+	Err error
+}
+
+func (e *UnmappedError) Error() string {
+	msg := fmt.Sprintf("%d:%d: no key mapping for %q (%U) at offset %d", e.Line, e.Col, e.Rune, e.Rune, e.Offset)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *UnmappedError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{ErrKeyMappingNotFound}
+	}
+	return []error{ErrKeyMappingNotFound, e.Err}
+}
+
+// UnmappedPolicy defines how characters are handled that cannot be typed.
+type UnmappedPolicy int
+
+const (
+	// UnmappedAbort returns an *UnmappedError for every character that cannot be typed
+	// before any key is pressed.
+	UnmappedAbort UnmappedPolicy = iota
+	// UnmappedSkip silently skips characters that cannot be typed.
+	UnmappedSkip
+	// UnmappedSubstitute types a substitute character instead, see WithSubstitute.
+	UnmappedSubstitute
+	// UnmappedFallback types characters with the Fallback, see WithFallback.
+	UnmappedFallback
+)
+
+// textChar is a character of a typed text.
+type textChar struct {
+	UnmappedRune
+	Index int // counted in characters
+}
+
+// textScanner iterates over the characters of a text and keeps track of their line and column.
+type textScanner struct {
+	line, col int
+}
+
+// pos returns the position of the character at the given offset and advances the scanner.
+func (s *textScanner) pos(offset int, r rune) UnmappedRune {
+	if s.line == 0 {
+		s.line, s.col = 1, 1
+	}
+	u := UnmappedRune{Rune: r, Offset: offset, Line: s.line, Col: s.col}
+	if r == '\n' {
+		s.line++
+		s.col = 1
+	} else {
+		s.col++
+	}
+	return u
+}
+
+// textPosition returns the position of the character at the given byte offset of the text.
+func textPosition(text string, offset int, r rune) UnmappedRune {
+	var s textScanner
+	for o, c := range text[:offset] {
+		s.pos(o, c)
+	}
+	return s.pos(offset, r)
+}
+
+// Validate returns all characters of the text that are not part of the KeyMap.
+func (m KeyMap) Validate(text string) []UnmappedRune {
+	var (
+		result []UnmappedRune
+		s      textScanner
+	)
+	for offset, r := range text {
+		u := s.pos(offset, r)
+		if _, ok := m[r]; !ok {
+			result = append(result, u)
+		}
+	}
+	return result
+}
+
+// Validate returns all characters of the text that can neither be typed with the KeyMap,
+// nor with the KeySequences, nor with the Fallback.
+// The UnmappedPolicy is not taken into account.
+func (kb *KBWrap) Validate(text string) []UnmappedRune {
+	var (
+		result []UnmappedRune
+		s      textScanner
+	)
+	for offset, r := range text {
+		u := s.pos(offset, r)
+		if _, err := kb.runeToKeys(r, true); err != nil {
+			result = append(result, u)
+		}
+	}
+	return result
+}
+
+// strToKeys translates every character of the string into the key events
+// that type the character according to the UnmappedPolicy.
+// In case of errors, no key events are returned.
+func (kb *KBWrap) strToKeys(str string) (keys [][]KeyEvent, err error) {
+	keys, _, errs := kb.resolve(str)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return keys, nil
+}
+
+// resolve translates all characters of the string that can be typed into key events
+// according to the UnmappedPolicy. chars contains the character of every entry of keys.
+// All other characters are returned as *UnmappedError.
+func (kb *KBWrap) resolve(str string) (keys [][]KeyEvent, chars []textChar, errs []error) {
+	var (
+		s     textScanner
+		index = -1
+	)
+	for offset, r := range str {
+		index++
+		u := s.pos(offset, r)
+		events, err := kb.runeToKeys(r, kb.policy == UnmappedFallback)
+		switch {
+		case err == nil:
+		case kb.policy == UnmappedSkip:
+			continue
+		case kb.policy == UnmappedSubstitute:
+			events, err = kb.runeToKeys(kb.substitute, false)
+		}
+		if err != nil {
+			kb.debug("unmapped", slog.String("char", string(r)), slog.Int("index", index), slog.Any("err", err))
+			errs = append(errs, newUnmappedError(u, err))
+			continue
+		}
+		kb.debug("resolved", slog.String("char", string(r)), slog.Int("index", index), slog.String("keys", eventKeys(events)))
+		keys = append(keys, events)
+		chars = append(chars, textChar{UnmappedRune: u, Index: index})
+	}
+	return keys, chars, errs
+}
+
+// runeToKeys translates a character into the key events that type the character.
+// Key sequences take precedence over the key map, which takes precedence over the KeysymMap.
+// Characters that are not mapped at all are typed with the fallback, if enabled.
+// The error is either ErrKeyMappingNotFound or the error of the fallback.
+func (kb *KBWrap) runeToKeys(r rune, fallback bool) ([]KeyEvent, error) {
+	if seq, ok := kb.sequences[r]; ok && len(seq) > 0 {
+		return Tap(seq...), nil
+	}
+	code, ok := kb.keyMap[r]
+	if ok {
+		return Tap(code), nil
+	}
+	if kb.keysyms != nil {
+		if ks, ok := kb.keysyms.Keysym(r); ok {
+			return Tap(KeysymKeyCode(ks)), nil
+		}
+	}
+	if !fallback || kb.fallback == nil {
+		return nil, ErrKeyMappingNotFound
+	}
+	return kb.fallback.Events(r, kb.keyMap)
+}
+
+// newUnmappedError creates the error of a character for an error of runeToKeys.
+func newUnmappedError(u UnmappedRune, err error) *UnmappedError {
+	if err == ErrKeyMappingNotFound {
+		err = nil
+	}
+	return &UnmappedError{UnmappedRune: u, Err: err}
+}
@@ -0,0 +1,107 @@
+package sendkeys
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestKeyMapValidate(t *testing.T) {
+	keyMap := KeyMap{'a': SimpleKeyCode(30), '\n': SimpleKeyCode(28)}
+
+	unmapped := keyMap.Validate("aä\n€a")
+	expected := []UnmappedRune{
+		{Rune: 'ä', Offset: 1, Line: 1, Col: 2},
+		{Rune: '€', Offset: 4, Line: 2, Col: 1},
+	}
+	if !reflect.DeepEqual(unmapped, expected) {
+		t.Fatalf("expected %v, got %v", expected, unmapped)
+	}
+}
+
+func TestUnmappedPolicy(t *testing.T) {
+	keyMap := KeyMap{'a': SimpleKeyCode(30), '\n': SimpleKeyCode(28), '?': ShiftKeyCode(53), 'u': SimpleKeyCode(22), ' ': SimpleKeyCode(57)}
+	for _, c := range "0123456789abcdef" {
+		if _, ok := keyMap[c]; !ok {
+			keyMap[c] = SimpleKeyCode(int(c))
+		}
+	}
+
+	t.Run("abort", func(t *testing.T) {
+		k, rec := newRecordingKBWrap(t, WithKeyMap(keyMap), Stubborn)
+		err := k.Type("aä\nx")
+
+		var uerr *UnmappedError
+		if !errors.As(err, &uerr) || !errors.Is(err, ErrKeyMappingNotFound) {
+			t.Fatalf("expected %T, got %v", uerr, err)
+		}
+		if uerr.UnmappedRune != (UnmappedRune{Rune: 'ä', Offset: 1, Line: 1, Col: 2}) {
+			t.Fatalf("unexpected position: %v", uerr)
+		}
+		if len(rec.Events()) != 0 {
+			t.Fatalf("expected no events, got %v", rec.Events())
+		}
+		// the error is not sticky
+		err = k.Type("a")
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		k, rec := newRecordingKBWrap(t, WithKeyMap(keyMap), WithUnmappedPolicy(UnmappedSkip))
+		err := k.Type("äaä")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if keys := rec.Keys(); !reflect.DeepEqual(keys, []KeyCode{keyMap['a']}) {
+			t.Fatalf("unexpected keys: %v", keys)
+		}
+	})
+
+	t.Run("substitute", func(t *testing.T) {
+		k, rec := newRecordingKBWrap(t, WithKeyMap(keyMap), WithSubstitute('?'))
+		err := k.Type("äa")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if keys := rec.Keys(); !reflect.DeepEqual(keys, []KeyCode{keyMap['?'], keyMap['a']}) {
+			t.Fatalf("unexpected keys: %v", keys)
+		}
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		k, rec := newRecordingKBWrap(t, WithKeyMap(keyMap), WithFallback(UnicodeHexInput()))
+		err := k.Type("ä")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// ctrl+shift+u e 4 space
+		if keys := rec.Keys(); len(keys) != 4 {
+			t.Fatalf("unexpected keys: %v", keys)
+		}
+		if unmapped := k.Validate("ä"); len(unmapped) != 0 {
+			t.Fatalf("expected no unmapped characters, got %v", unmapped)
+		}
+	})
+
+	t.Run("fallback without Fallback", func(t *testing.T) {
+		_, err := NewKBWrapWithOptions(WithBackend(NewRecorder()), WithUnmappedPolicy(UnmappedFallback))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("send", func(t *testing.T) {
+		k, _ := newRecordingKBWrap(t, WithKeyMap(keyMap))
+		err := k.Send("a\n{ENTER}ä")
+
+		var uerr *UnmappedError
+		if !errors.As(err, &uerr) {
+			t.Fatalf("expected %T, got %v", uerr, err)
+		}
+		if uerr.UnmappedRune != (UnmappedRune{Rune: 'ä', Offset: 9, Line: 2, Col: 8}) {
+			t.Fatalf("unexpected position: %v", uerr)
+		}
+	})
+}
@@ -0,0 +1,735 @@
+package sendkeys
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// XKBSymbolsDir is the default location of the XKB symbols files on Linux systems.
+const XKBSymbolsDir = "/usr/share/X11/xkb/symbols"
+
+// ErrInvalidXKB is returned when an XKB symbols file cannot be parsed.
+var ErrInvalidXKB = errors.New("invalid xkb symbols")
+
+// maximum depth of nested include statements
+const xkbMaxIncludeDepth = 16
+
+// xkbKeyCodes maps XKB key names to linux evdev key codes,
+// which are the key codes that are used by KeyMapLinuxQuartz.
+var xkbKeyCodes = map[string]int{
+	"ESC":  1,
+	"AE01": 2, "AE02": 3, "AE03": 4, "AE04": 5, "AE05": 6, "AE06": 7,
+	"AE07": 8, "AE08": 9, "AE09": 10, "AE10": 11, "AE11": 12, "AE12": 13,
+	"BKSP": 14,
+	"TAB":  15,
+	"AD01": 16, "AD02": 17, "AD03": 18, "AD04": 19, "AD05": 20, "AD06": 21,
+	"AD07": 22, "AD08": 23, "AD09": 24, "AD10": 25, "AD11": 26, "AD12": 27,
+	"RTRN": 28,
+	"LCTL": 29,
+	"AC01": 30, "AC02": 31, "AC03": 32, "AC04": 33, "AC05": 34, "AC06": 35,
+	"AC07": 36, "AC08": 37, "AC09": 38, "AC10": 39, "AC11": 40,
+	"TLDE": 41,
+	"LFSH": 42,
+	"BKSL": 43, "AC12": 43,
+	"AB01": 44, "AB02": 45, "AB03": 46, "AB04": 47, "AB05": 48,
+	"AB06": 49, "AB07": 50, "AB08": 51, "AB09": 52, "AB10": 53,
+	"RTSH": 54,
+	"KPMU": 55,
+	"LALT": 56,
+	"SPCE": 57,
+	"CAPS": 58,
+	"FK01": 59, "FK02": 60, "FK03": 61, "FK04": 62, "FK05": 63,
+	"FK06": 64, "FK07": 65, "FK08": 66, "FK09": 67, "FK10": 68,
+	"NMLK": 69,
+	"SCLK": 70,
+	"KP7":  71, "KP8": 72, "KP9": 73, "KPSU": 74,
+	"KP4": 75, "KP5": 76, "KP6": 77, "KPAD": 78,
+	"KP1": 79, "KP2": 80, "KP3": 81, "KP0": 82, "KPDL": 83,
+	"LSGT": 86,
+	"FK11": 87, "FK12": 88,
+	"AB11": 89,
+	"KPEN": 96,
+	"RCTL": 97,
+	"KPDV": 98,
+	"PRSC": 99,
+	"RALT": 100,
+	"HOME": 102, "UP": 103, "PGUP": 104, "LEFT": 105, "RGHT": 106,
+	"END": 107, "DOWN": 108, "PGDN": 109, "INS": 110, "DELE": 111,
+	"KPEQ": 117,
+	"KPCO": 121,
+	"AE13": 124,
+	"LWIN": 125, "LMTA": 125,
+	"RWIN": 126, "RMTA": 126,
+	"COMP": 127, "MENU": 127,
+	"FK13": 183, "FK14": 184, "FK15": 185, "FK16": 186, "FK17": 187, "FK18": 188,
+	"FK19": 189, "FK20": 190, "FK21": 191, "FK22": 192, "FK23": 193, "FK24": 194,
+}
+
+// keysyms that produce a character without being a printable keysym
+var xkbControlKeysyms = map[uint32]rune{
+	0xff0d: '\n', // Return
+}
+
+// LoadXKBLayout creates a KeyMap from the XKB symbols files that are installed
+// on the local system. See ParseXKBLayout for the layout format.
+func LoadXKBLayout(layout string) (KeyMap, error) {
+	return ParseXKBLayout(os.DirFS(XKBSymbolsDir), layout)
+}
+
+// ParseXKBLayout creates a KeyMap for a linux machine from XKB symbols files.
+// fsys must contain the symbols files at its root, e.g. os.DirFS("/usr/share/X11/xkb/symbols").
+// layout is either a file name like "us", which selects the default section of the file,
+// or a file name with a variant like "fr(azerty)". Include statements are resolved within fsys.
+// The first four shift levels of the first group are mapped to
+// no modifier, Shift, AltGr and AltGr+Shift.
+// Space and Return are added with their evdev key codes in case the layout does not define them.
+func ParseXKBLayout(fsys fs.FS, layout string) (KeyMap, error) {
+	r := &xkbResolver{
+		fsys:  fsys,
+		files: map[string][]*xkbSection{},
+	}
+	keys, err := r.resolve(layout, 0)
+	if err != nil {
+		return nil, err
+	}
+	return keys.keyMap(), nil
+}
+
+type xkbMergeMode int
+
+const (
+	xkbOverride xkbMergeMode = iota
+	xkbAugment
+	xkbReplace
+)
+
+func parseXKBMergeMode(s string) (xkbMergeMode, bool) {
+	switch s {
+	case "include", "override":
+		return xkbOverride, true
+	case "augment":
+		return xkbAugment, true
+	case "replace":
+		return xkbReplace, true
+	}
+	return 0, false
+}
+
+// xkbKeys maps key names to the keysym names of the levels of their first group.
+type xkbKeys map[string][]string
+
+// merge merges the levels of a key. The levels are copied, as they may belong to
+// a cached section that is included more than once.
+func (k xkbKeys) merge(mode xkbMergeMode, name string, levels []string) {
+	old, ok := k[name]
+	if !ok || mode == xkbReplace {
+		k[name] = slices.Clone(levels)
+		return
+	}
+
+	old = slices.Clone(old)
+	if len(levels) > len(old) {
+		old = append(old, make([]string, len(levels)-len(old))...)
+	}
+	for i, level := range levels {
+		if level == "" || level == "NoSymbol" {
+			continue
+		}
+		if mode == xkbAugment && old[i] != "" && old[i] != "NoSymbol" {
+			continue
+		}
+		old[i] = level
+	}
+	k[name] = old
+}
+
+func (k xkbKeys) mergeAll(mode xkbMergeMode, other xkbKeys) {
+	for name, levels := range other {
+		k.merge(mode, name, levels)
+	}
+}
+
+func (k xkbKeys) keyMap() KeyMap {
+	type key struct {
+		code   int
+		levels []string
+	}
+	keys := make([]key, 0, len(k))
+	for name, levels := range k {
+		code, ok := xkbKeyCodes[name]
+		if !ok {
+			continue
+		}
+		keys = append(keys, key{code, levels})
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].code < keys[j].code
+	})
+
+	result := KeyMap{}
+	// lower levels take precedence over higher levels
+	for level := 0; level < 4; level++ {
+		for _, key := range keys {
+			if level >= len(key.levels) {
+				continue
+			}
+			ks, ok := parseKeysym(key.levels[level])
+			if !ok {
+				continue
+			}
+			r, ok := xkbControlKeysyms[ks]
+			if !ok {
+				r, ok = keysymToRune(ks)
+			}
+			if !ok || r < 0x20 && r != '\n' {
+				continue
+			}
+			if _, ok := result[r]; ok {
+				continue
+			}
+			result[r] = KeyCode{
+				Code:          key.code,
+				ModifierSHIFT: level%2 == 1,
+				ModifierALTGR: level >= 2,
+			}
+		}
+	}
+
+	if _, ok := result[' ']; !ok {
+		result[' '] = SimpleKeyCode(xkbKeyCodes["SPCE"])
+	}
+	if _, ok := result['\n']; !ok {
+		result['\n'] = SimpleKeyCode(xkbKeyCodes["RTRN"])
+	}
+	return result
+}
+
+type xkbResolver struct {
+	fsys  fs.FS
+	files map[string][]*xkbSection
+}
+
+// resolve evaluates an include string like "pc+us(intl)+inet(evdev)".
+func (r *xkbResolver) resolve(include string, depth int) (xkbKeys, error) {
+	if depth > xkbMaxIncludeDepth {
+		return nil, fmt.Errorf("%w: include depth exceeded: %s", ErrInvalidXKB, include)
+	}
+
+	result := xkbKeys{}
+	mode := xkbOverride
+	for len(include) > 0 {
+		end := strings.IndexAny(include, "+|")
+		if end < 0 {
+			end = len(include)
+		}
+		part := include[:end]
+
+		keys, err := r.resolvePart(part, depth)
+		if err != nil {
+			return nil, err
+		}
+		result.mergeAll(mode, keys)
+
+		if end == len(include) {
+			break
+		}
+		if include[end] == '|' {
+			mode = xkbAugment
+		} else {
+			mode = xkbOverride
+		}
+		include = include[end+1:]
+	}
+	return result, nil
+}
+
+// resolvePart evaluates a single include like "fr(azerty)" or "us:2".
+func (r *xkbResolver) resolvePart(part string, depth int) (xkbKeys, error) {
+	part = strings.TrimSpace(part)
+	if i := strings.IndexByte(part, ':'); i >= 0 {
+		if part[i+1:] != "1" {
+			// only the first group is used
+			return xkbKeys{}, nil
+		}
+		part = part[:i]
+	}
+
+	file, variant := part, ""
+	if i := strings.IndexByte(part, '('); i >= 0 {
+		if !strings.HasSuffix(part, ")") {
+			return nil, fmt.Errorf("%w: invalid include: %q", ErrInvalidXKB, part)
+		}
+		file, variant = part[:i], part[i+1:len(part)-1]
+	}
+	if file == "" || !fs.ValidPath(file) {
+		return nil, fmt.Errorf("%w: invalid include: %q", ErrInvalidXKB, part)
+	}
+
+	sections, err := r.load(file)
+	if err != nil {
+		return nil, err
+	}
+
+	section, err := findXKBSection(sections, variant)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidXKB, part, err)
+	}
+	return r.evaluate(section, depth)
+}
+
+func (r *xkbResolver) load(file string) ([]*xkbSection, error) {
+	if sections, ok := r.files[file]; ok {
+		return sections, nil
+	}
+
+	data, err := fs.ReadFile(r.fsys, file)
+	if err != nil {
+		return nil, err
+	}
+	sections, err := parseXKBSymbols(path.Base(file), string(data))
+	if err != nil {
+		return nil, err
+	}
+	r.files[file] = sections
+	return sections, nil
+}
+
+func findXKBSection(sections []*xkbSection, variant string) (*xkbSection, error) {
+	if len(sections) == 0 {
+		return nil, errors.New("no xkb_symbols section found")
+	}
+	if variant == "" {
+		for _, s := range sections {
+			if s.isDefault {
+				return s, nil
+			}
+		}
+		return sections[0], nil
+	}
+	for _, s := range sections {
+		if s.name == variant {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("variant not found: %s", variant)
+}
+
+func (r *xkbResolver) evaluate(section *xkbSection, depth int) (xkbKeys, error) {
+	result := xkbKeys{}
+	for _, stmt := range section.stmts {
+		if stmt.include == "" {
+			result.merge(stmt.mode, stmt.key, stmt.levels)
+			continue
+		}
+		keys, err := r.resolve(stmt.include, depth+1)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", section.file, stmt.line, err)
+		}
+		result.mergeAll(stmt.mode, keys)
+	}
+	return result, nil
+}
+
+type xkbSection struct {
+	file      string
+	name      string
+	isDefault bool
+	stmts     []xkbStmt
+}
+
+// xkbStmt is either an include statement or a key definition.
+type xkbStmt struct {
+	line    int
+	mode    xkbMergeMode
+	include string
+	key     string
+	levels  []string
+}
+
+type xkbToken struct {
+	text string
+	line int
+	str  bool // quoted string
+}
+
+func tokenizeXKB(file, data string) ([]xkbToken, error) {
+	var (
+		tokens []xkbToken
+		line   = 1
+	)
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#' || strings.HasPrefix(data[i:], "//"):
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(data[i:], "/*"):
+			end := strings.Index(data[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("%w: %s:%d: unterminated comment", ErrInvalidXKB, file, line)
+			}
+			line += strings.Count(data[i:i+2+end], "\n")
+			i += end + 4
+		case c == '"':
+			end := strings.IndexByte(data[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: %s:%d: unterminated string", ErrInvalidXKB, file, line)
+			}
+			tokens = append(tokens, xkbToken{text: data[i+1 : i+1+end], line: line, str: true})
+			line += strings.Count(data[i+1:i+1+end], "\n")
+			i += end + 2
+		case c == '<':
+			end := strings.IndexByte(data[i:], '>')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: %s:%d: unterminated key name", ErrInvalidXKB, file, line)
+			}
+			tokens = append(tokens, xkbToken{text: data[i : i+end+1], line: line})
+			i += end + 1
+		case isXKBIdent(c):
+			start := i
+			for i < len(data) && isXKBIdent(data[i]) {
+				i++
+			}
+			tokens = append(tokens, xkbToken{text: data[start:i], line: line})
+		default:
+			tokens = append(tokens, xkbToken{text: string(c), line: line})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+func isXKBIdent(c byte) bool {
+	return c == '_' || c == '.' || c == '-' ||
+		'a' <= c && c <= 'z' ||
+		'A' <= c && c <= 'Z' ||
+		'0' <= c && c <= '9'
+}
+
+type xkbParser struct {
+	file   string
+	tokens []xkbToken
+	pos    int
+}
+
+func parseXKBSymbols(file, data string) ([]*xkbSection, error) {
+	tokens, err := tokenizeXKB(file, data)
+	if err != nil {
+		return nil, err
+	}
+	p := &xkbParser{file: file, tokens: tokens}
+	return p.parseFile()
+}
+
+func (p *xkbParser) eof() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *xkbParser) peek() xkbToken {
+	if p.eof() {
+		line := 0
+		if len(p.tokens) > 0 {
+			line = p.tokens[len(p.tokens)-1].line
+		}
+		return xkbToken{line: line}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *xkbParser) next() xkbToken {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *xkbParser) errorf(t xkbToken, format string, args ...any) error {
+	return fmt.Errorf("%w: %s:%d: %s", ErrInvalidXKB, p.file, t.line, fmt.Sprintf(format, args...))
+}
+
+func (p *xkbParser) expect(text string) error {
+	t := p.next()
+	if t.str || t.text != text {
+		if t.text == "" && !t.str {
+			return p.errorf(t, "expected %q, got end of file", text)
+		}
+		return p.errorf(t, "expected %q, got %q", text, t.text)
+	}
+	return nil
+}
+
+func (p *xkbParser) parseFile() ([]*xkbSection, error) {
+	var (
+		sections  []*xkbSection
+		isDefault bool
+	)
+	for !p.eof() {
+		t := p.next()
+		switch {
+		case t.text == "default" && !t.str:
+			isDefault = true
+		case t.text == "xkb_symbols" && !t.str:
+			name := p.next()
+			if !name.str {
+				return nil, p.errorf(name, "expected section name, got %q", name.text)
+			}
+			section := &xkbSection{
+				file:      p.file,
+				name:      name.text,
+				isDefault: isDefault,
+			}
+			err := p.parseSection(section)
+			if err != nil {
+				return nil, err
+			}
+			sections = append(sections, section)
+			isDefault = false
+		case t.str || t.text == "{" || t.text == "}":
+			return nil, p.errorf(t, "unexpected %q", t.text)
+		default:
+			// flags like partial, hidden or alphanumeric_keys
+		}
+	}
+	return sections, nil
+}
+
+func (p *xkbParser) parseSection(section *xkbSection) error {
+	err := p.expect("{")
+	if err != nil {
+		return err
+	}
+
+	for {
+		t := p.peek()
+		switch {
+		case p.eof():
+			return p.errorf(t, "unterminated section %q", section.name)
+		case t.text == "}" && !t.str:
+			p.next()
+			if p.peek().text == ";" {
+				p.next()
+			}
+			return nil
+		case t.text == ";" && !t.str:
+			p.next()
+		default:
+			err = p.parseStatement(section)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (p *xkbParser) parseStatement(section *xkbSection) error {
+	t := p.next()
+	mode := xkbOverride
+	if m, ok := parseXKBMergeMode(t.text); ok && !t.str {
+		if p.peek().str {
+			include := p.next()
+			section.stmts = append(section.stmts, xkbStmt{
+				line:    t.line,
+				mode:    m,
+				include: include.text,
+			})
+			return nil
+		}
+		mode = m
+		t = p.next()
+	}
+
+	if t.text == "key" && !t.str {
+		name := p.next()
+		if !strings.HasPrefix(name.text, "<") {
+			return p.errorf(name, "expected key name, got %q", name.text)
+		}
+		levels, err := p.parseKeyBody()
+		if err != nil {
+			return err
+		}
+		section.stmts = append(section.stmts, xkbStmt{
+			line:   t.line,
+			mode:   mode,
+			key:    strings.Trim(name.text, "<>"),
+			levels: levels,
+		})
+		return p.expect(";")
+	}
+
+	// skip unsupported statements like name[Group1]="..."; or modifier_map
+	return p.skipStatement()
+}
+
+// skipStatement skips all tokens until the next semicolon outside of braces.
+func (p *xkbParser) skipStatement() error {
+	depth := 0
+	for !p.eof() {
+		t := p.next()
+		if t.str {
+			continue
+		}
+		switch t.text {
+		case "{", "[", "(":
+			depth++
+		case "}", "]", ")":
+			depth--
+			if depth < 0 {
+				return p.errorf(t, "unexpected %q", t.text)
+			}
+		case ";":
+			if depth == 0 {
+				return nil
+			}
+		}
+	}
+	return p.errorf(p.peek(), "unexpected end of file")
+}
+
+// parseKeyBody parses the body of a key definition and returns the symbols of the first group.
+func (p *xkbParser) parseKeyBody() ([]string, error) {
+	err := p.expect("{")
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		group  = 0
+		levels []string
+	)
+	for {
+		t := p.next()
+		switch {
+		case p.eof() && t.text == "":
+			return nil, p.errorf(t, "unterminated key definition")
+		case t.str:
+			return nil, p.errorf(t, "unexpected string %q", t.text)
+		case t.text == "}":
+			return levels, nil
+		case t.text == ",":
+		case t.text == "[":
+			// implicit group
+			group++
+			symbols, err := p.parseSymbols()
+			if err != nil {
+				return nil, err
+			}
+			if group == 1 {
+				levels = symbols
+			}
+		default:
+			// field assignment like type[Group1]="..." or symbols[Group1]=[...]
+			field := t.text
+			index := ""
+			if p.peek().text == "[" {
+				p.next()
+				index = p.next().text
+				err = p.expect("]")
+				if err != nil {
+					return nil, err
+				}
+			}
+			err = p.expect("=")
+			if err != nil {
+				return nil, err
+			}
+			if field != "symbols" || p.peek().text != "[" {
+				err = p.skipValue()
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			p.next()
+			symbols, err := p.parseSymbols()
+			if err != nil {
+				return nil, err
+			}
+			if strings.EqualFold(index, "Group1") || index == "1" {
+				levels = symbols
+			}
+		}
+	}
+}
+
+// parseSymbols parses a list of keysym names up to the closing bracket.
+func (p *xkbParser) parseSymbols() ([]string, error) {
+	var symbols []string
+	for {
+		t := p.next()
+		switch {
+		case t.str || t.text == "":
+			return nil, p.errorf(t, "expected keysym, got %q", t.text)
+		case t.text == "]":
+			return symbols, nil
+		case t.text == ",":
+		case t.text == "{":
+			// multiple keysyms per level are not supported
+			err := p.skipUntil("}")
+			if err != nil {
+				return nil, err
+			}
+			symbols = append(symbols, "NoSymbol")
+		case !isXKBIdent(t.text[0]):
+			return nil, p.errorf(t, "expected keysym, got %q", t.text)
+		default:
+			symbols = append(symbols, t.text)
+		}
+	}
+}
+
+// skipValue skips a single value of a field assignment.
+func (p *xkbParser) skipValue() error {
+	t := p.next()
+	if t.str {
+		return nil
+	}
+	switch t.text {
+	case "[":
+		return p.skipUntil("]")
+	case "{":
+		return p.skipUntil("}")
+	case "":
+		return p.errorf(t, "unexpected end of file")
+	}
+	if p.peek().text == "(" {
+		p.next()
+		return p.skipUntil(")")
+	}
+	return nil
+}
+
+// skipUntil skips all tokens up to the matching closing token.
+func (p *xkbParser) skipUntil(closing string) error {
+	depth := 0
+	for !p.eof() {
+		t := p.next()
+		if t.str {
+			continue
+		}
+		switch t.text {
+		case "{", "[", "(":
+			depth++
+		case "}", "]", ")":
+			if depth == 0 {
+				if t.text != closing {
+					return p.errorf(t, "expected %q, got %q", closing, t.text)
+				}
+				return nil
+			}
+			depth--
+		}
+	}
+	return p.errorf(p.peek(), "expected %q, got end of file", closing)
+}
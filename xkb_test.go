@@ -0,0 +1,149 @@
+package sendkeys
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+var xkbTestSymbols = os.DirFS("testdata/xkb/symbols")
+
+func TestParseXKBLayoutUS(t *testing.T) {
+	keyMap, err := ParseXKBLayout(xkbTestSymbols, "us")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := KeyMapLinuxQuartz()
+	if keyMap.String() != expected.String() {
+		t.Fatalf("expected us layout to match KeyMapLinuxQuartz, got:\n%s", keyMap)
+	}
+}
+
+func TestParseXKBLayoutLevels(t *testing.T) {
+	tests := map[string]map[rune]KeyCode{
+		"us(euro)": {
+			'5': SimpleKeyCode(6),
+			'%': ShiftKeyCode(6),
+			'€': AltGrKeyCode(6),
+			'|': ShiftKeyCode(43),
+		},
+		"de": {
+			'ß':  SimpleKeyCode(12),
+			'?':  ShiftKeyCode(12),
+			'\\': AltGrKeyCode(12),
+			'"':  ShiftKeyCode(3),
+			'²':  AltGrKeyCode(3),
+			'@':  AltGrKeyCode(16),
+			'€':  AltGrKeyCode(18),
+			'z':  SimpleKeyCode(21),
+			'y':  SimpleKeyCode(44),
+			'ü':  SimpleKeyCode(26),
+			'Ü':  ShiftKeyCode(26),
+			'µ':  AltGrKeyCode(50),
+			'¿':  AltGrShiftKeyCode(12),
+			'\n': SimpleKeyCode(28),
+			' ':  SimpleKeyCode(57),
+		},
+		"fr(azerty)": {
+			'&': SimpleKeyCode(2),
+			'1': ShiftKeyCode(2),
+			'é': SimpleKeyCode(3),
+			'~': AltGrKeyCode(3),
+			'a': SimpleKeyCode(16),
+			'q': SimpleKeyCode(30),
+			'M': ShiftKeyCode(39),
+		},
+	}
+
+	for layout, expected := range tests {
+		t.Run(layout, func(t *testing.T) {
+			keyMap, err := ParseXKBLayout(xkbTestSymbols, layout)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for r, code := range expected {
+				if keyMap[r] != code {
+					t.Errorf("%q: expected %s, got %s", r, code, keyMap[r])
+				}
+			}
+		})
+	}
+}
+
+func TestParseXKBLayoutRoundTrip(t *testing.T) {
+	keyMap, err := ParseXKBLayout(xkbTestSymbols, "de")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k, rec := newRecordingKBWrap(t, WithKeyMap(keyMap))
+	teststr := "Grüße an Jürgen: 5€ @ home!"
+	err = k.Type(teststr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	typed, err := rec.Text(keyMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typed != teststr {
+		t.Fatalf("[FAIL] Have: %q, Wanted: %q", typed, teststr)
+	}
+}
+
+func TestParseXKBLayoutErrors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"broken":  {Data: []byte("xkb_symbols \"basic\" {\n\n key <AE01> { [ 1, exclam };\n};\n")},
+		"missing": {Data: []byte("xkb_symbols \"basic\" {\n include \"nothere\"\n};\n")},
+		"loop":    {Data: []byte("xkb_symbols \"basic\" {\n include \"loop\"\n};\n")},
+	}
+
+	_, err := ParseXKBLayout(fsys, "broken")
+	if !errors.Is(err, ErrInvalidXKB) || !strings.Contains(err.Error(), "broken:3") {
+		t.Errorf("expected %v with line number, got %v", ErrInvalidXKB, err)
+	}
+
+	_, err = ParseXKBLayout(fsys, "missing")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected %v, got %v", fs.ErrNotExist, err)
+	}
+
+	_, err = ParseXKBLayout(fsys, "loop")
+	if !errors.Is(err, ErrInvalidXKB) {
+		t.Errorf("expected %v, got %v", ErrInvalidXKB, err)
+	}
+
+	_, err = ParseXKBLayout(xkbTestSymbols, "us(nothere)")
+	if !errors.Is(err, ErrInvalidXKB) {
+		t.Errorf("expected %v, got %v", ErrInvalidXKB, err)
+	}
+}
+
+func TestParseXKBLayoutIncludeTwice(t *testing.T) {
+	fsys := fstest.MapFS{
+		"base": {Data: []byte("xkb_symbols \"basic\" {\n key <AC01> { [ a, A ] };\n key <AE01> { [ 1, exclam ] };\n};\n")},
+		"over": {Data: []byte("xkb_symbols \"basic\" {\n key <AC01> { [ q, Q ] };\n};\n")},
+	}
+
+	keyMap, err := ParseXKBLayout(fsys, "base+over+base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for r, expected := range map[rune]KeyCode{
+		'a': SimpleKeyCode(30),
+		'A': ShiftKeyCode(30),
+		'1': SimpleKeyCode(2),
+		'!': ShiftKeyCode(2),
+	} {
+		if keyMap[r] != expected {
+			t.Errorf("%q: expected %v, got %v", r, expected, keyMap[r])
+		}
+	}
+	if _, ok := keyMap['q']; ok {
+		t.Error("expected the override to be replaced by the second include of base")
+	}
+}